@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/authz"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+const (
+	msgSendTypeURL           = "/cosmos.bank.v1beta1.MsgSend"
+	sendAuthorizationTypeURL = "/cosmos.bank.v1beta1.SendAuthorization"
+)
+
+// AuthzGranter returns the address the coins are sent from in authz mode or an empty string
+func (c *Client) AuthzGranter() string {
+	return c.authzGranter
+}
+
+// authzMsgs sends the bank sends on behalf of the authz granter in a single MsgExec signed by the wallet,
+// CW20 transfers are still sent from the wallet
+func (c *Client) authzMsgs(grantee string, msgs []sdk.Msg) ([]sdk.Msg, error) {
+	if c.authzGranter == "" {
+		return msgs, nil
+	}
+	exec := &authz.MsgExec{Grantee: grantee}
+	others := make([]sdk.Msg, 0, len(msgs))
+	for _, msg := range msgs {
+		send, ok := msg.(*banktypes.MsgSend)
+		if !ok {
+			others = append(others, msg)
+			continue
+		}
+		send.FromAddress = c.authzGranter
+		sendAny, err := codectypes.NewAnyWithValue(send)
+		if err != nil {
+			return nil, err
+		}
+		exec.Msgs = append(exec.Msgs, sendAny)
+	}
+	if len(exec.Msgs) == 0 {
+		return others, nil
+	}
+	return append([]sdk.Msg{exec}, others...), nil
+}
+
+// AuthzLimit returns the remaining spend limit of the send authorization the granter gave to a wallet,
+// it's empty if there is no authorization
+func (c *Client) AuthzLimit(ctx context.Context, grantee string) (sdk.Coins, error) {
+	var grants AuthzGrantsResponse
+	query := url.Values{"granter": {c.authzGranter}, "grantee": {grantee}, "msg_type_url": {msgSendTypeURL}}
+	err := c.getJSON(ctx, fmt.Sprintf("%s/cosmos/authz/v1beta1/grants?%s", c.apiEndpoint, query.Encode()), &grants)
+	if isNotFound(err) {
+		return sdk.NewCoins(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(grants.Grants) == 0 {
+		return sdk.NewCoins(), nil
+	}
+	authorization := grants.Grants[0].Authorization
+	if authorization.Type != sendAuthorizationTypeURL {
+		return nil, fmt.Errorf("unsupported authorization %s, the faucet needs a send authorization", authorization.Type)
+	}
+	return authorization.SpendLimit, nil
+}
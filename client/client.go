@@ -2,11 +2,17 @@ package client
 
 import (
 	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"cosmossdk.io/core/address"
 	"github.com/cosmos/cosmos-sdk/client"
 	"github.com/cosmos/cosmos-sdk/client/tx"
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/public-awesome/faucet/client/ethsecp256k1"
 )
 
 type Client struct {
@@ -18,10 +24,37 @@ type Client struct {
 	chainID         string
 	gasPrices       string
 	gasAmount       int64
-	account         string
+	gasAdjustment   float64
+	simulateGas     bool
+	accounts        uint32
+	// treasury is funded separately and tops up the hot wallets, either from its own mnemonic or a derivation index of the faucet mnemonic
+	treasuryMnemonics string
+	treasuryIndex     *uint32
+	// confirmTimeout is how long to wait for a transaction to be included in a block, zero disables confirmation
+	confirmTimeout time.Duration
+	// feeMode selects how the gas price is discovered, see the FeeMode constants
+	feeMode       string
+	feeCacheTTL   time.Duration
+	maxGasPrice   string
+	gasPriceCache gasPriceCache
 
 	txConfig  client.TxConfig
 	txFactory tx.Factory
+	// addressCodec encodes addresses with the chain prefix, the global sdk config is never used
+	addressCodec address.Codec
+	// keyAlgo is the key algorithm of the wallets, see the KeyAlgo constants
+	keyAlgo string
+	// authzGranter holds the funds and granted the wallets a send authorization, bank sends are executed on its behalf
+	authzGranter string
+
+	wallets  []*wallet
+	treasury *wallet
+	next     atomic.Uint32
+	// released is closed and replaced every time a wallet is released
+	releasedMu sync.Mutex
+	released   chan struct{}
+	// blockGas caches the gas limit of a block, gas estimates never exceed it
+	blockGas blockGasCache
 }
 
 type ClientOption func(*Client)
@@ -74,22 +107,134 @@ func WithGasAmount(gasAmount int64) ClientOption {
 	}
 }
 
-func New(opts ...ClientOption) *Client {
-	c := &Client{coinType: 118}
+func WithGasAdjustment(gasAdjustment float64) ClientOption {
+	return func(c *Client) {
+		c.gasAdjustment = gasAdjustment
+	}
+}
+
+// WithSimulateGas enables gas estimation through simulation, the fixed gas amount is used as a fallback
+func WithSimulateGas(simulate bool) ClientOption {
+	return func(c *Client) {
+		c.simulateGas = simulate
+	}
+}
+
+// WithFeeMode sets how the gas price is discovered, the gas prices are used as the fallback and to select the fee denom
+func WithFeeMode(mode string, cacheTTL time.Duration, maxGasPrice string) ClientOption {
+	return func(c *Client) {
+		c.feeMode = mode
+		c.feeCacheTTL = cacheTTL
+		c.maxGasPrice = maxGasPrice
+	}
+}
+
+// WithAccounts sets the number of hot wallets derived from the mnemonic
+func WithAccounts(accounts uint32) ClientOption {
+	return func(c *Client) {
+		c.accounts = accounts
+	}
+}
+
+func WithTreasuryMnemonics(mnemonics string) ClientOption {
+	return func(c *Client) {
+		c.treasuryMnemonics = mnemonics
+	}
+}
+
+func WithTreasuryIndex(index *uint32) ClientOption {
+	return func(c *Client) {
+		c.treasuryIndex = index
+	}
+}
+
+func WithConfirmTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.confirmTimeout = timeout
+	}
+}
+
+// WithKeyAlgo sets the key algorithm of the wallets, chains with eth_secp256k1 keys usually also use coin type 60
+func WithKeyAlgo(algo string) ClientOption {
+	return func(c *Client) {
+		c.keyAlgo = algo
+	}
+}
+
+// WithAuthzGranter sends the coins from the granter of the wallets' send authorization, the wallets only pay the fees
+func WithAuthzGranter(granter string) ClientOption {
+	return func(c *Client) {
+		c.authzGranter = granter
+	}
+}
+
+// New returns a client with the wallets derived from the mnemonics, an error is returned if the key
+// algorithm, a mnemonic or the treasury index is invalid
+func New(opts ...ClientOption) (*Client, error) {
+	c := &Client{coinType: 118, accounts: 1, gasAdjustment: 1.5, keyAlgo: KeyAlgoSecp256k1, released: make(chan struct{})}
 	for _, opt := range opts {
 		opt(c)
 	}
-	c.setupFactory()
-	return c
+	err := c.setupFactory()
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
-func (c *Client) BankSend(ctx context.Context, address, amount string) (string, error) {
-	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+func (c *Client) BankSend(ctx context.Context, address, amount string) (*TxResult, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Second+c.confirmTimeout)
 	defer cancel()
 	return c.transfer(timeoutCtx, c.txFactory, c.txConfig, address, amount)
 }
 
+// BankMultiSend sends to every recipient in a single transaction with one MsgSend per recipient
+func (c *Client) BankMultiSend(ctx context.Context, sends []Send) (*TxResult, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Second+c.confirmTimeout)
+	defer cancel()
+	return c.multiTransfer(timeoutCtx, c.txFactory, c.txConfig, sends)
+}
+
+// TopUp sends coins from the treasury to a hot wallet
+func (c *Client) TopUp(ctx context.Context, address string, coins sdk.Coins) (*TxResult, error) {
+	if c.treasury == nil {
+		return nil, errors.New("no treasury configured")
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Second+c.confirmTimeout)
+	defer cancel()
+	return c.topUp(timeoutCtx, c.txFactory, c.txConfig, address, coins)
+}
+
+// EstimatedFee returns the fee paid by a transaction with a single message using the fixed gas amount
+func (c *Client) EstimatedFee(ctx context.Context) (sdk.Coin, error) {
+	return c.fee(ctx, uint64(c.gasAmount))
+}
+
+// TreasuryAddress returns the treasury address or an empty string if there is no treasury
+func (c *Client) TreasuryAddress() string {
+	if c.treasury == nil {
+		return ""
+	}
+	return c.treasury.address
+}
+
 func (c *Client) ValidAddress(address string) bool {
-	_, err := sdk.GetFromBech32(address, c.accountPrefix)
+	_, err := c.NormalizeAddress(address)
 	return err == nil
 }
+
+// NormalizeAddress returns the bech32 form of an address, chains with eth_secp256k1 keys also accept 0x addresses
+func (c *Client) NormalizeAddress(address string) (string, error) {
+	if c.keyAlgo == KeyAlgoEthSecp256k1 && strings.HasPrefix(strings.ToLower(address), "0x") {
+		bz, err := ethsecp256k1.ParseHexAddress(address)
+		if err != nil {
+			return "", err
+		}
+		return c.addressCodec.BytesToString(bz)
+	}
+	_, err := sdk.GetFromBech32(address, c.accountPrefix)
+	if err != nil {
+		return "", err
+	}
+	return address, nil
+}
@@ -0,0 +1,509 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cosmossdk.io/x/feegrant"
+	"github.com/cosmos/cosmos-sdk/client/tx"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+	"github.com/cosmos/cosmos-sdk/x/authz"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/public-awesome/faucet/client/wasm"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+func newClient(t *testing.T, opts ...ClientOption) *Client {
+	t.Helper()
+	c, err := New(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestNewErrors(t *testing.T) {
+	index := uint32(1)
+	for name, opts := range map[string][]ClientOption{
+		"key algo":       {WithKeyAlgo("ed25519")},
+		"mnemonic":       {WithFaucetMnemonics("not a mnemonic")},
+		"treasury index": {WithAccounts(2), WithTreasuryIndex(&index)},
+	} {
+		_, err := New(append([]ClientOption{WithAccountPrefix("stars"), WithFaucetMnemonics(testMnemonic)}, opts...)...)
+		if err == nil {
+			t.Fatalf("expected an error for an invalid %s", name)
+		}
+	}
+}
+
+// signTx signs the messages with the wallet at account number and sequence 1 and returns the encoded transaction
+func signTx(t *testing.T, c *Client, w *wallet, msgs ...sdk.Msg) (authsigning.Tx, []byte) {
+	t.Helper()
+	factory := c.txFactory.WithAccountNumber(1).WithSequence(1).WithGas(200000)
+	txb, err := factory.BuildUnsignedTx(msgs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tx.Sign(context.Background(), factory, w.name, txb, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txBytes, err := c.txConfig.TxEncoder()(txb.GetTx())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return txb.GetTx(), txBytes
+}
+
+func TestSignTx(t *testing.T) {
+	granter := newClient(t, WithAccountPrefix("stars"), WithFaucetMnemonics(testMnemonic), WithCoinType(60)).Addresses()[0]
+	contract := sdk.MustBech32ifyAddressBytes("stars", bytes.Repeat([]byte{1}, 32))
+	stars := []ClientOption{WithAccountPrefix("stars"), WithFaucetMnemonics(testMnemonic), WithChainID("elgafar-1")}
+	for _, tc := range []struct {
+		name string
+		opts []ClientOption
+		msgs func(t *testing.T, c *Client, from string) []sdk.Msg
+	}{
+		{
+			name: "bank send",
+			opts: stars,
+			msgs: func(t *testing.T, c *Client, from string) []sdk.Msg {
+				return []sdk.Msg{&banktypes.MsgSend{FromAddress: from, ToAddress: from, Amount: sdk.NewCoins(sdk.NewInt64Coin("ustars", 1))}}
+			},
+		},
+		{
+			name: "other prefix",
+			opts: []ClientOption{WithAccountPrefix("osmo"), WithFaucetMnemonics(testMnemonic), WithChainID("osmo-test-5")},
+			msgs: func(t *testing.T, c *Client, from string) []sdk.Msg {
+				return []sdk.Msg{&banktypes.MsgSend{FromAddress: from, ToAddress: from, Amount: sdk.NewCoins(sdk.NewInt64Coin("uosmo", 1))}}
+			},
+		},
+		{
+			name: "eth_secp256k1",
+			opts: []ClientOption{WithAccountPrefix("evmos"), WithFaucetMnemonics(testMnemonic), WithChainID("evmos_9000-4"), WithCoinType(60), WithKeyAlgo(KeyAlgoEthSecp256k1)},
+			msgs: func(t *testing.T, c *Client, from string) []sdk.Msg {
+				return []sdk.Msg{&banktypes.MsgSend{FromAddress: from, ToAddress: from, Amount: sdk.NewCoins(sdk.NewInt64Coin("atevmos", 1))}}
+			},
+		},
+		{
+			name: "cw20 transfer",
+			opts: stars,
+			msgs: func(t *testing.T, c *Client, from string) []sdk.Msg {
+				return sendMsgs(t, c, from, "10ustars,cw20:"+contract+":1000")
+			},
+		},
+		{
+			name: "fee grant",
+			opts: stars,
+			msgs: func(t *testing.T, c *Client, from string) []sdk.Msg {
+				allowance, err := FeeAllowance{SpendLimit: "1000ustars", Expiration: time.Hour, Period: time.Minute, PeriodSpendLimit: "100ustars"}.allowance(time.Now())
+				if err != nil {
+					t.Fatal(err)
+				}
+				return []sdk.Msg{
+					&feegrant.MsgRevokeAllowance{Granter: from, Grantee: granter},
+					&feegrant.MsgGrantAllowance{Granter: from, Grantee: granter, Allowance: allowance},
+				}
+			},
+		},
+		{
+			name: "authz send",
+			opts: append(stars, WithAuthzGranter(granter)),
+			msgs: func(t *testing.T, c *Client, from string) []sdk.Msg {
+				msgs, err := c.authzMsgs(from, sendMsgs(t, c, from, "10ustars,cw20:"+contract+":1000"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return msgs
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newClient(t, tc.opts...)
+			w := c.wallets[0]
+			signed, txBytes := signTx(t, c, w, tc.msgs(t, c, w.address)...)
+
+			pubKeys, err := signed.GetPubKeys()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pubKeys[0].Type() != c.keyAlgo {
+				t.Fatalf("expected an %s public key, got %s", c.keyAlgo, pubKeys[0].Type())
+			}
+			sigs, err := signed.GetSignaturesV2()
+			if err != nil {
+				t.Fatal(err)
+			}
+			signerData := authsigning.SignerData{Address: w.address, ChainID: c.chainID, AccountNumber: 1, Sequence: 1, PubKey: pubKeys[0]}
+			signBytes, err := authsigning.GetSignBytesAdapter(context.Background(), c.txConfig.SignModeHandler(), signing.SignMode_SIGN_MODE_DIRECT, signerData, signed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !pubKeys[0].VerifySignature(signBytes, sigs[0].Data.(*signing.SingleSignatureData).Signature) {
+				t.Fatal("invalid signature")
+			}
+			if len(txBytes) == 0 {
+				t.Fatal("expected an encoded transaction")
+			}
+		})
+	}
+}
+
+// sendMsgs returns the messages sending the amount back to the wallet
+func sendMsgs(t *testing.T, c *Client, from, amount string) []sdk.Msg {
+	t.Helper()
+	coins, err := ParseCoins(amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs, err := c.sendMsgs(from, from, coins)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msgs
+}
+
+func TestClientsWithDifferentPrefixes(t *testing.T) {
+	stars := newClient(t, WithAccountPrefix("stars"), WithFaucetMnemonics(testMnemonic), WithChainID("elgafar-1"))
+	osmo := newClient(t, WithAccountPrefix("osmo"), WithFaucetMnemonics(testMnemonic), WithChainID("osmo-test-5"))
+
+	if !strings.HasPrefix(stars.Addresses()[0], "stars1") || !strings.HasPrefix(osmo.Addresses()[0], "osmo1") {
+		t.Fatalf("unexpected addresses %s and %s", stars.Addresses()[0], osmo.Addresses()[0])
+	}
+	if !stars.ValidAddress(stars.Addresses()[0]) || stars.ValidAddress(osmo.Addresses()[0]) {
+		t.Fatal("address validation must use the client prefix")
+	}
+}
+
+func TestEthSecp256k1Client(t *testing.T) {
+	c := newClient(t, WithAccountPrefix("evmos"), WithFaucetMnemonics(testMnemonic), WithChainID("evmos_9000-4"), WithCoinType(60), WithKeyAlgo(KeyAlgoEthSecp256k1))
+
+	// the first account of the mnemonic in ethereum wallets
+	hex := "0x9858EfFD232B4033E47d90003D41EC34EcaEda94"
+	bech32, err := c.NormalizeAddress(hex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bech32 != c.Addresses()[0] {
+		t.Fatalf("expected %s to be the wallet address %s", bech32, c.Addresses()[0])
+	}
+	if !c.ValidAddress(strings.ToLower(hex)) || !c.ValidAddress(bech32) {
+		t.Fatal("both address forms must be valid")
+	}
+	if c.ValidAddress("0x9858efFD232B4033E47d90003D41EC34EcaEda94") {
+		t.Fatal("mixed case addresses must have a valid checksum")
+	}
+}
+
+func TestCW20Sends(t *testing.T) {
+	c := newClient(t, WithAccountPrefix("stars"), WithFaucetMnemonics(testMnemonic), WithChainID("elgafar-1"))
+	contract, err := c.addressCodec.BytesToString(bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	coins, err := ParseCoins("10ustars, cw20:" + contract + ":1000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if coins.AmountOf(CW20Prefix+contract).Int64() != 1000 || coins.AmountOf("ustars").Int64() != 10 {
+		t.Fatalf("unexpected coins %s", coins)
+	}
+	_, err = ParseCoins("cw20:1000")
+	if err == nil {
+		t.Fatal("expected an error for a cw20 coin without a contract")
+	}
+
+	w := c.wallets[0]
+	msgs, err := c.sendMsgs(w.address, w.address, coins)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("expected a bank send and a cw20 transfer, got %d messages", len(msgs))
+	}
+	execute := msgs[1].(*wasm.MsgExecuteContract)
+	expected := `{"transfer":{"amount":"1000","recipient":"` + w.address + `"}}`
+	if execute.Contract != contract || execute.Sender != w.address || string(execute.Msg) != expected {
+		t.Fatalf("unexpected execute message %s", execute.String())
+	}
+}
+
+func TestIBCPacketStatus(t *testing.T) {
+	const receiver = "osmo1receiver"
+	acknowledged := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/cosmos/tx/v1beta1/txs/HASH":
+			fmt.Fprint(w, `{"tx_response":{"txhash":"HASH","code":0,"events":[
+				{"type":"send_packet","attributes":[{"key":"packet_sequence","value":"7"}]},
+				{"type":"send_packet","attributes":[{"key":"packet_sequence","value":"8"}]}]}}`)
+		case r.URL.Path == "/cosmos/tx/v1beta1/txs/MISSING":
+			http.NotFound(w, r)
+		case strings.HasSuffix(r.URL.Path, "/packet_commitments/7"):
+			fmt.Fprint(w, `{"commitment":"abc"}`)
+		case strings.Contains(r.URL.Path, "/packet_commitments/"):
+			http.NotFound(w, r)
+		case r.URL.Path == "/cosmos/tx/v1beta1/txs":
+			query := r.URL.Query().Get("query")
+			if strings.HasPrefix(query, "acknowledge_packet") {
+				for sequence, ackError := range acknowledged {
+					if strings.Contains(query, "'"+sequence+"'") {
+						fmt.Fprintf(w, `{"tx_responses":[{"events":[{"type":"fungible_token_packet","attributes":[
+							{"key":"receiver","value":"%s"},{"key":"error","value":"%s"}]}]}]}`, receiver, ackError)
+						return
+					}
+				}
+			}
+			if strings.HasPrefix(query, "timeout_packet") && strings.Contains(query, "'9'") {
+				fmt.Fprint(w, `{"tx_responses":[{}]}`)
+				return
+			}
+			fmt.Fprint(w, `{"tx_responses":[]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := newClient(t, WithAccountPrefix("stars"), WithFaucetMnemonics(testMnemonic), WithChainID("elgafar-1"), WithAPI(srv.URL))
+	ctx := context.Background()
+	sequences, err := c.PacketSequences(ctx, "HASH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sequences) != 2 || sequences[0] != 7 || sequences[1] != 8 {
+		t.Fatalf("unexpected sequences %v", sequences)
+	}
+	_, err = c.PacketSequences(ctx, "MISSING")
+	if !errors.Is(err, ErrTxNotFound) {
+		t.Fatalf("expected ErrTxNotFound, got %v", err)
+	}
+
+	acknowledged["8"] = ""
+	acknowledged["10"] = "insufficient funds"
+	// the commitment of packet 11 is gone but neither its acknowledgement nor its timeout is indexed
+	for sequence, expected := range map[uint64]string{7: PacketPending, 8: PacketAcknowledged, 9: PacketTimedOut, 10: PacketFailed, 11: PacketUnknown} {
+		status, err := c.PacketStatus(ctx, "transfer", "channel-0", receiver, sequence)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status != expected {
+			t.Fatalf("expected packet %d to be %s, got %s", sequence, expected, status)
+		}
+	}
+}
+
+func TestFeeGrant(t *testing.T) {
+	granted := ""
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if granted == "" {
+			fmt.Fprint(w, `{"allowances":[{"granter":"stars1other","grantee":"stars1grantee"}]}`)
+			return
+		}
+		fmt.Fprintf(w, `{"allowances":[{"granter":"stars1other"},{"granter":"%s"}]}`, granted)
+	}))
+	defer srv.Close()
+
+	c := newClient(t, WithAccountPrefix("stars"), WithFaucetMnemonics(testMnemonic), WithChainID("elgafar-1"), WithAPI(srv.URL), WithAccounts(2))
+	ctx := context.Background()
+	granter, err := c.FeeGranter(ctx, "stars1grantee")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if granter != "" {
+		t.Fatalf("expected no faucet granter, got %s", granter)
+	}
+	granted = c.wallets[1].address
+	granter, err = c.FeeGranter(ctx, "stars1grantee")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if granter != granted {
+		t.Fatalf("expected granter %s, got %s", granted, granter)
+	}
+
+	now := time.Now()
+	allowanceAny, err := FeeAllowance{SpendLimit: "1000ustars", Expiration: time.Hour, Period: time.Minute, PeriodSpendLimit: "100ustars"}.allowance(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	periodic, ok := allowanceAny.GetCachedValue().(*feegrant.PeriodicAllowance)
+	if !ok {
+		t.Fatalf("expected a periodic allowance, got %T", allowanceAny.GetCachedValue())
+	}
+	if !periodic.Basic.Expiration.Equal(now.Add(time.Hour)) || periodic.PeriodCanSpend.String() != "100ustars" {
+		t.Fatalf("unexpected allowance %s", periodic)
+	}
+	_, err = FeeAllowance{SpendLimit: "100ustars", Period: time.Minute, PeriodSpendLimit: "100uatom"}.allowance(now)
+	if err == nil {
+		t.Fatal("expected an error for a period limit in another denom")
+	}
+}
+
+func TestAuthzSends(t *testing.T) {
+	granter := newClient(t, WithAccountPrefix("stars"), WithFaucetMnemonics(testMnemonic), WithChainID("elgafar-1"), WithCoinType(60)).wallets[0].address
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("granter") != granter || r.URL.Query().Get("msg_type_url") != "/cosmos.bank.v1beta1.MsgSend" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"grants":[{"authorization":{"@type":"/cosmos.bank.v1beta1.SendAuthorization","spend_limit":[{"denom":"ustars","amount":"5000"}]}}]}`)
+	}))
+	defer srv.Close()
+
+	c := newClient(t, WithAccountPrefix("stars"), WithFaucetMnemonics(testMnemonic), WithChainID("elgafar-1"), WithAPI(srv.URL), WithAuthzGranter(granter))
+	ctx := context.Background()
+	w := c.wallets[0]
+	limit, err := c.AuthzLimit(ctx, w.address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limit.String() != "5000ustars" {
+		t.Fatalf("unexpected authz limit %s", limit)
+	}
+
+	contract, err := c.addressCodec.BytesToString(bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	coins, err := ParseCoins("10ustars,cw20:" + contract + ":1000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs, err := c.sendMsgs(w.address, w.address, coins)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs, err = c.authzMsgs(w.address, msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("expected an exec and a cw20 transfer, got %d messages", len(msgs))
+	}
+	exec, ok := msgs[0].(*authz.MsgExec)
+	if !ok || exec.Grantee != w.address || len(exec.Msgs) != 1 {
+		t.Fatalf("unexpected exec message %v", msgs[0])
+	}
+	send, ok := exec.Msgs[0].GetCachedValue().(*banktypes.MsgSend)
+	if !ok || send.FromAddress != granter {
+		t.Fatalf("expected a send from the granter, got %v", exec.Msgs[0].GetCachedValue())
+	}
+}
+
+func TestGasPrice(t *testing.T) {
+	var (
+		queries  int
+		response string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries++
+		if response == "" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, response)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name string
+		// response is the feemarket gas price, the query fails if it's empty
+		response string
+		maxPrice string
+		expected string
+	}{
+		{name: "discovered", response: `{"price":{"denom":"ustars","amount":"0.05"}}`, expected: "0.050000000000000000ustars"},
+		{name: "capped", response: `{"price":{"denom":"ustars","amount":"0.5"}}`, maxPrice: "0.2ustars", expected: "0.200000000000000000ustars"},
+		{name: "other denom", response: `{"price":{"denom":"uatom","amount":"0.05"}}`, maxPrice: "0.2ustars", expected: "0.100000000000000000ustars"},
+		{name: "discovery failure", expected: "0.100000000000000000ustars"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			queries = 0
+			response = tc.response
+			c := newClient(t, WithAccountPrefix("stars"), WithFaucetMnemonics(testMnemonic), WithAPI(srv.URL),
+				WithGasPrices("0.1ustars"), WithFeeMode(FeeModeFeeMarket, time.Minute, tc.maxPrice))
+			for i := 0; i < 2; i++ {
+				gasPrice, err := c.gasPrice(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if gasPrice.String() != tc.expected {
+					t.Fatalf("expected gas price %s, got %s", tc.expected, gasPrice)
+				}
+			}
+			if queries != 1 {
+				t.Fatalf("expected the gas price to be cached, got %d queries", queries)
+			}
+		})
+	}
+
+	c := newClient(t, WithAccountPrefix("stars"), WithFaucetMnemonics(testMnemonic), WithAPI(srv.URL),
+		WithGasPrices("0.1ustars"), WithFeeMode(FeeModeFeeMarket, time.Minute, "0.2uatom"))
+	_, err := c.gasPrice(ctx)
+	if err == nil {
+		t.Fatal("expected an error for a cap in another denom")
+	}
+}
+
+func TestEstimateGas(t *testing.T) {
+	var (
+		simulated string
+		maxGas    string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cosmos/tx/v1beta1/simulate":
+			if simulated == "" {
+				http.Error(w, "out of gas", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprintf(w, `{"gas_info":{"gas_used":%q}}`, simulated)
+		case "/cosmos/consensus/v1/params":
+			fmt.Fprintf(w, `{"params":{"block":{"max_bytes":"22020096","max_gas":%q}}}`, maxGas)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		name string
+		// simulated is the gas used by the simulation, the simulation fails if it's empty
+		simulated string
+		maxGas    string
+		msgs      int
+		expected  uint64
+	}{
+		{name: "simulated", simulated: "80000", maxGas: "-1", msgs: 1, expected: 120000},
+		{name: "simulation failure", maxGas: "-1", msgs: 3, expected: 300000},
+		{name: "fallback capped by the block gas limit", maxGas: "1000000", msgs: 20, expected: 1000000},
+		{name: "simulation capped by the block gas limit", simulated: "900000", maxGas: "1000000", msgs: 20, expected: 1000000},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			simulated, maxGas = tc.simulated, tc.maxGas
+			c := newClient(t, WithAccountPrefix("stars"), WithFaucetMnemonics(testMnemonic), WithChainID("elgafar-1"), WithAPI(srv.URL),
+				WithGasPrices("0.1ustars"), WithGasAmount(100000), WithGasAdjustment(1.5), WithSimulateGas(true))
+			w := c.wallets[0]
+			msgs := make([]sdk.Msg, 0, tc.msgs)
+			for i := 0; i < tc.msgs; i++ {
+				msgs = append(msgs, &banktypes.MsgSend{FromAddress: w.address, ToAddress: w.address, Amount: sdk.NewCoins(sdk.NewInt64Coin("ustars", 1))})
+			}
+			factory := c.txFactory.WithAccountNumber(1).WithSequence(1).WithFromName(w.name)
+			if gas := c.estimateGas(context.Background(), factory, msgs...); gas != tc.expected {
+				t.Fatalf("expected %d gas, got %d", tc.expected, gas)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	sdkmath "cosmossdk.io/math"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/public-awesome/faucet/client/wasm"
+)
+
+// CW20Prefix is the prefix of the denom of CW20 tokens, the contract address follows it
+const CW20Prefix = "cw20:"
+
+// ParseCoins parses a comma separated list of coins where CW20 tokens are written as
+// cw20:<contract>:<amount>, the tokens are returned as coins with a cw20:<contract> denom
+func ParseCoins(amount string) (sdk.Coins, error) {
+	entries := strings.Split(amount, ",")
+	for i, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if !strings.HasPrefix(entry, CW20Prefix) {
+			continue
+		}
+		contract, value, ok := strings.Cut(strings.TrimPrefix(entry, CW20Prefix), ":")
+		if !ok || contract == "" {
+			return nil, fmt.Errorf("invalid cw20 coin %q, expected cw20:<contract>:<amount>", entry)
+		}
+		entries[i] = value + CW20Prefix + contract
+	}
+	return sdk.ParseCoinsNormalized(strings.Join(entries, ","))
+}
+
+// CW20Contract returns the contract address of a CW20 denom
+func CW20Contract(denom string) (string, bool) {
+	if !strings.HasPrefix(denom, CW20Prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(denom, CW20Prefix), true
+}
+
+// sendMsgs returns a bank send for the native coins and a CW20 transfer for each token
+func (c *Client) sendMsgs(from, to string, coins sdk.Coins) ([]sdk.Msg, error) {
+	var (
+		native sdk.Coins
+		msgs   []sdk.Msg
+	)
+	for _, coin := range coins {
+		contract, ok := CW20Contract(coin.Denom)
+		if !ok {
+			native = native.Add(coin)
+			continue
+		}
+		_, err := c.addressCodec.StringToBytes(contract)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cw20 contract %s: %w", contract, err)
+		}
+		transfer, err := json.Marshal(map[string]any{
+			"transfer": map[string]string{"recipient": to, "amount": coin.Amount.String()},
+		})
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, &wasm.MsgExecuteContract{Sender: from, Contract: contract, Msg: transfer})
+	}
+	if !native.Empty() {
+		msgs = append([]sdk.Msg{&banktypes.MsgSend{FromAddress: from, ToAddress: to, Amount: native}}, msgs...)
+	}
+	return msgs, nil
+}
+
+// CW20Balance returns the balance of an address in a CW20 contract
+func (c *Client) CW20Balance(ctx context.Context, contract, address string) (sdkmath.Int, error) {
+	query, err := json.Marshal(map[string]any{"balance": map[string]string{"address": address}})
+	if err != nil {
+		return sdkmath.Int{}, err
+	}
+	var balance CW20BalanceResponse
+	err = c.getJSON(ctx, fmt.Sprintf("%s/cosmwasm/wasm/v1/contract/%s/smart/%s", c.apiEndpoint, contract, base64.URLEncoding.EncodeToString(query)), &balance)
+	if err != nil {
+		return sdkmath.Int{}, err
+	}
+	return balance.Data.Balance, nil
+}
@@ -0,0 +1,268 @@
+// Package ethsecp256k1 implements the eth_secp256k1 keys of ethermint based chains such as Evmos and
+// Cronos. The keys are secp256k1 keys that sign the keccak256 hash of the message and whose address
+// is the ethereum address, so the same mnemonic controls the account on the cosmos and evm sides.
+package ethsecp256k1
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/cometbft/cometbft/crypto"
+	"github.com/cosmos/cosmos-sdk/crypto/hd"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	"github.com/cosmos/gogoproto/proto"
+	secp256k1 "github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"golang.org/x/crypto/sha3"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	// KeyType is the key type of the keyring and of the public keys
+	KeyType = "eth_secp256k1"
+	// PrivKeySize is the size of the private key in bytes
+	PrivKeySize = 32
+	// PubKeySize is the size of the compressed public key in bytes
+	PubKeySize = 33
+
+	// PubKeyName and PrivKeyName are the protobuf names used by ethermint
+	PubKeyName  = "ethermint.crypto.v1.ethsecp256k1.PubKey"
+	PrivKeyName = "ethermint.crypto.v1.ethsecp256k1.PrivKey"
+)
+
+func init() {
+	proto.RegisterType((*PubKey)(nil), PubKeyName)
+	proto.RegisterType((*PrivKey)(nil), PrivKeyName)
+}
+
+var (
+	_ cryptotypes.PubKey  = &PubKey{}
+	_ cryptotypes.PrivKey = &PrivKey{}
+)
+
+// Keccak256 returns the legacy keccak256 hash used by ethereum
+func Keccak256(data ...[]byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	for _, b := range data {
+		h.Write(b)
+	}
+	return h.Sum(nil)
+}
+
+// PubKey is a compressed secp256k1 public key with an ethereum address
+type PubKey struct {
+	Key []byte
+}
+
+// Address returns the last 20 bytes of the keccak256 hash of the uncompressed key, an invalid key has
+// no address. Decoded keys are validated by Unmarshal
+func (pubKey *PubKey) Address() crypto.Address {
+	pub, err := secp256k1.ParsePubKey(pubKey.Key)
+	if err != nil {
+		return nil
+	}
+	return crypto.Address(Keccak256(pub.SerializeUncompressed()[1:])[12:])
+}
+
+func (pubKey *PubKey) Bytes() []byte {
+	return pubKey.Key
+}
+
+// VerifySignature verifies a signature of the form R || S || V over the keccak256 hash of the message,
+// the recovery id V is optional
+func (pubKey *PubKey) VerifySignature(msg, sig []byte) bool {
+	if len(sig) == 65 {
+		sig = sig[:64]
+	}
+	if len(sig) != 64 {
+		return false
+	}
+	pub, err := secp256k1.ParsePubKey(pubKey.Key)
+	if err != nil {
+		return false
+	}
+	var r, s secp256k1.ModNScalar
+	if r.SetByteSlice(sig[:32]) || s.SetByteSlice(sig[32:]) || s.IsOverHalfOrder() {
+		return false
+	}
+	return ecdsa.NewSignature(&r, &s).Verify(Keccak256(msg), pub)
+}
+
+func (pubKey *PubKey) Equals(other cryptotypes.PubKey) bool {
+	return pubKey.Type() == other.Type() && bytes.Equal(pubKey.Bytes(), other.Bytes())
+}
+
+func (pubKey *PubKey) Type() string {
+	return KeyType
+}
+
+func (pubKey *PubKey) Reset()                   { *pubKey = PubKey{} }
+func (pubKey *PubKey) String() string           { return fmt.Sprintf("EthPubKeySecp256k1{%X}", pubKey.Key) }
+func (*PubKey) ProtoMessage()                   {}
+func (*PubKey) XXX_MessageName() string         { return PubKeyName }
+func (pubKey *PubKey) Size() int                { return sizeKey(pubKey.Key) }
+func (pubKey *PubKey) Marshal() ([]byte, error) { return marshalKey(pubKey.Key), nil }
+func (pubKey *PubKey) Unmarshal(b []byte) error {
+	key, err := unmarshalKey(b)
+	if err != nil {
+		return err
+	}
+	_, err = secp256k1.ParsePubKey(key)
+	if err != nil {
+		return fmt.Errorf("invalid eth_secp256k1 public key: %w", err)
+	}
+	pubKey.Key = key
+	return nil
+}
+
+// PrivKey is a secp256k1 private key that signs keccak256 hashes
+type PrivKey struct {
+	Key []byte
+}
+
+func (privKey *PrivKey) Bytes() []byte {
+	return privKey.Key
+}
+
+// Sign returns a recoverable signature of the form R || S || V over the keccak256 hash of the message
+func (privKey *PrivKey) Sign(msg []byte) ([]byte, error) {
+	if len(privKey.Key) != PrivKeySize {
+		return nil, fmt.Errorf("invalid private key length %d", len(privKey.Key))
+	}
+	priv := secp256k1.PrivKeyFromBytes(privKey.Key)
+	// the compact signature is V || R || S with V offset by 27
+	sig := ecdsa.SignCompact(priv, Keccak256(msg), false)
+	return append(sig[1:], sig[0]-27), nil
+}
+
+func (privKey *PrivKey) PubKey() cryptotypes.PubKey {
+	priv := secp256k1.PrivKeyFromBytes(privKey.Key)
+	return &PubKey{Key: priv.PubKey().SerializeCompressed()}
+}
+
+func (privKey *PrivKey) Equals(other cryptotypes.LedgerPrivKey) bool {
+	return privKey.Type() == other.Type() && subtle.ConstantTimeCompare(privKey.Bytes(), other.Bytes()) == 1
+}
+
+func (privKey *PrivKey) Type() string {
+	return KeyType
+}
+
+func (privKey *PrivKey) Reset()                   { *privKey = PrivKey{} }
+func (privKey *PrivKey) String() string           { return "EthPrivKeySecp256k1{...}" }
+func (*PrivKey) ProtoMessage()                    {}
+func (*PrivKey) XXX_MessageName() string          { return PrivKeyName }
+func (privKey *PrivKey) Size() int                { return sizeKey(privKey.Key) }
+func (privKey *PrivKey) Marshal() ([]byte, error) { return marshalKey(privKey.Key), nil }
+func (privKey *PrivKey) Unmarshal(b []byte) error {
+	key, err := unmarshalKey(b)
+	if err != nil {
+		return err
+	}
+	if len(key) != PrivKeySize {
+		return fmt.Errorf("invalid eth_secp256k1 private key length %d", len(key))
+	}
+	privKey.Key = key
+	return nil
+}
+
+// both keys are encoded as a message with a single bytes field `key = 1`
+
+func sizeKey(key []byte) int {
+	if len(key) == 0 {
+		return 0
+	}
+	return protowire.SizeTag(1) + protowire.SizeBytes(len(key))
+}
+
+func marshalKey(key []byte) []byte {
+	if len(key) == 0 {
+		return []byte{}
+	}
+	b := protowire.AppendTag(nil, 1, protowire.BytesType)
+	return protowire.AppendBytes(b, key)
+}
+
+func unmarshalKey(b []byte) ([]byte, error) {
+	var key []byte
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		if num == 1 && typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			key = bytes.Clone(v)
+			b = b[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return key, nil
+}
+
+// HexAddress returns the EIP-55 checksummed hex form of an address
+func HexAddress(addr []byte) string {
+	lower := hex.EncodeToString(addr)
+	hash := Keccak256([]byte(lower))
+	out := []byte(lower)
+	for i, c := range out {
+		nibble := hash[i/2] >> 4
+		if i%2 == 1 {
+			nibble = hash[i/2] & 0x0f
+		}
+		if c >= 'a' && nibble >= 8 {
+			out[i] = c - 'a' + 'A'
+		}
+	}
+	return "0x" + string(out)
+}
+
+// ParseHexAddress parses a 0x address, mixed case addresses must have a valid EIP-55 checksum
+func ParseHexAddress(address string) ([]byte, error) {
+	if len(address) != 42 || (address[:2] != "0x" && address[:2] != "0X") {
+		return nil, fmt.Errorf("invalid hex address %q", address)
+	}
+	addr, err := hex.DecodeString(address[2:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex address %q: %w", address, err)
+	}
+	digits := address[2:]
+	if digits != strings.ToLower(digits) && digits != strings.ToUpper(digits) && HexAddress(addr)[2:] != digits {
+		return nil, fmt.Errorf("invalid checksum of hex address %q", address)
+	}
+	return addr, nil
+}
+
+// Algo is the keyring algorithm of eth_secp256k1 keys, the derivation is the same BIP32 derivation as
+// secp256k1 so the coin type 60 path gives the same key as ethereum wallets
+var Algo = ethSecp256k1Algo{}
+
+type ethSecp256k1Algo struct{}
+
+func (ethSecp256k1Algo) Name() hd.PubKeyType {
+	return hd.PubKeyType(KeyType)
+}
+
+func (ethSecp256k1Algo) Derive() hd.DeriveFn {
+	return hd.Secp256k1.Derive()
+}
+
+func (ethSecp256k1Algo) Generate() hd.GenerateFn {
+	return func(bz []byte) cryptotypes.PrivKey {
+		key := make([]byte, PrivKeySize)
+		copy(key, bz)
+		return &PrivKey{Key: key}
+	}
+}
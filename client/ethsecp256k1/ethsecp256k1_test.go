@@ -0,0 +1,68 @@
+package ethsecp256k1
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+// the vectors come from the web3.js accounts documentation and EIP-55
+
+func TestAddressAndSignature(t *testing.T) {
+	key, err := hex.DecodeString("4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318")
+	if err != nil {
+		t.Fatal(err)
+	}
+	privKey := &PrivKey{Key: key}
+	pubKey := privKey.PubKey()
+	if address := HexAddress(pubKey.Address()); address != "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23" {
+		t.Fatalf("unexpected address %s", address)
+	}
+
+	// web3.eth.accounts.sign prefixes the message before hashing it and encodes V as 27 + the recovery id
+	msg := []byte(fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len("Some data"), "Some data"))
+	sig, err := privKey.Sign(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "b91467e570a6466aa9e9876cbcd013baba02900b8979d43fe208a4a4f339f5fd6007e74cd82e037b800186422fc2da167c747ef045e5d18a5f5d4300f8e1a02901"
+	if hex.EncodeToString(sig) != expected {
+		t.Fatalf("unexpected signature %x", sig)
+	}
+	if !pubKey.VerifySignature(msg, sig) || pubKey.VerifySignature([]byte("other data"), sig) {
+		t.Fatal("unexpected signature verification")
+	}
+}
+
+func TestHexAddress(t *testing.T) {
+	for _, address := range []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+		"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+		"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+	} {
+		addr, err := ParseHexAddress(address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if HexAddress(addr) != address {
+			t.Fatalf("expected checksum %s, got %s", address, HexAddress(addr))
+		}
+	}
+	if _, err := ParseHexAddress("0x5aaeb6053F3E94C9b9A09f33669435E7Ef1BeAed"); err == nil {
+		t.Fatal("expected an error for an invalid checksum")
+	}
+}
+
+func TestInvalidKeys(t *testing.T) {
+	pubKey := &PubKey{Key: []byte{2, 1, 2, 3}}
+	if pubKey.Address() != nil {
+		t.Fatal("expected no address for an invalid public key")
+	}
+	if err := new(PubKey).Unmarshal(marshalKey(pubKey.Key)); err == nil {
+		t.Fatal("expected an error decoding an invalid public key")
+	}
+	if err := new(PrivKey).Unmarshal(marshalKey([]byte{1, 2, 3})); err == nil {
+		t.Fatal("expected an error decoding a private key of the wrong length")
+	}
+}
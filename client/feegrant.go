@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cosmossdk.io/x/feegrant"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/gogoproto/proto"
+)
+
+// FeeAllowance is the fee allowance granted to an address, it's a periodic allowance when Period is set
+type FeeAllowance struct {
+	// SpendLimit is the total amount of fees the grantee can spend
+	SpendLimit string
+	// Expiration is how long the allowance is valid after it's granted
+	Expiration time.Duration
+	// Period is the period of a periodic allowance, PeriodSpendLimit can be spent in each period
+	Period           time.Duration
+	PeriodSpendLimit string
+}
+
+// allowance returns the x/feegrant allowance granted at the given time
+func (a FeeAllowance) allowance(now time.Time) (*codectypes.Any, error) {
+	spendLimit, err := sdk.ParseCoinsNormalized(a.SpendLimit)
+	if err != nil {
+		return nil, err
+	}
+	basic := feegrant.BasicAllowance{SpendLimit: spendLimit}
+	if a.Expiration > 0 {
+		expiration := now.Add(a.Expiration)
+		basic.Expiration = &expiration
+	}
+	var allowance feegrant.FeeAllowanceI = &basic
+	if a.Period > 0 {
+		periodSpendLimit, err := sdk.ParseCoinsNormalized(a.PeriodSpendLimit)
+		if err != nil {
+			return nil, err
+		}
+		allowance = &feegrant.PeriodicAllowance{
+			Basic:            basic,
+			Period:           a.Period,
+			PeriodSpendLimit: periodSpendLimit,
+			PeriodCanSpend:   periodSpendLimit,
+			PeriodReset:      now.Add(a.Period),
+		}
+	}
+	err = allowance.ValidateBasic()
+	if err != nil {
+		return nil, err
+	}
+	return codectypes.NewAnyWithValue(allowance.(proto.Message))
+}
+
+// GrantFeeAllowance grants a fee allowance from a faucet wallet and returns the granter. The chain keeps
+// one allowance per granter and grantee, an allowance granted by a faucet wallet earlier is renewed by
+// the same wallet so the grantee never holds several allowances from the faucet
+func (c *Client) GrantFeeAllowance(ctx context.Context, grantee string, allowance FeeAllowance) (*TxResult, string, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Second+c.confirmTimeout)
+	defer cancel()
+	grantee, err := c.NormalizeAddress(grantee)
+	if err != nil {
+		return nil, "", err
+	}
+	allowanceAny, err := allowance.allowance(time.Now())
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid fee allowance: %w", err)
+	}
+	granter, err := c.FeeGranter(timeoutCtx, grantee)
+	if err != nil {
+		return nil, "", err
+	}
+	var w *wallet
+	if granter != "" {
+		w, err = c.acquireAddress(timeoutCtx, granter)
+	} else {
+		w, err = c.acquire(timeoutCtx)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	var msgs []sdk.Msg
+	if granter != "" {
+		msgs = append(msgs, &feegrant.MsgRevokeAllowance{Granter: w.address, Grantee: grantee})
+	}
+	msgs = append(msgs, &feegrant.MsgGrantAllowance{Granter: w.address, Grantee: grantee, Allowance: allowanceAny})
+	result, err := c.broadcast(timeoutCtx, w, c.txFactory, c.txConfig, msgs...)
+	return result, w.address, err
+}
+
+// FeeGranter returns the faucet wallet that granted a fee allowance to the grantee, it's empty if there is none
+func (c *Client) FeeGranter(ctx context.Context, grantee string) (string, error) {
+	var allowances FeeAllowancesResponse
+	err := c.getJSON(ctx, fmt.Sprintf("%s/cosmos/feegrant/v1beta1/allowances/%s?pagination.limit=1000", c.apiEndpoint, grantee), &allowances)
+	if err != nil {
+		return "", fmt.Errorf("failed to query fee allowances: %w", err)
+	}
+	for _, grant := range allowances.Allowances {
+		for _, w := range c.wallets {
+			if grant.Granter == w.address {
+				return w.address, nil
+			}
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,177 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+const (
+	// FeeModeStatic uses the configured gas prices
+	FeeModeStatic = "static"
+	// FeeModeNode uses the minimum gas price of the node
+	FeeModeNode = "node"
+	// FeeModeFeeMarket uses the gas price of the feemarket module
+	FeeModeFeeMarket = "feemarket"
+	// FeeModeAuto uses the feemarket gas price if the module is present and the node minimum gas price otherwise
+	FeeModeAuto = "auto"
+)
+
+// ValidFeeMode reports whether the fee mode is one of the FeeMode constants
+func ValidFeeMode(mode string) bool {
+	switch mode {
+	case FeeModeStatic, FeeModeNode, FeeModeFeeMarket, FeeModeAuto:
+		return true
+	}
+	return false
+}
+
+// gasPriceCache holds the last discovered gas price
+type gasPriceCache struct {
+	mu      sync.Mutex
+	price   sdk.DecCoin
+	fetched time.Time
+}
+
+// gasPrice returns the gas price for the fee mode, discovered prices are capped and cached. If discovery
+// fails or returns a price in another denom than the configured gas prices, the configured gas prices are
+// used and cached as well so a failing endpoint isn't queried for every transaction
+func (c *Client) gasPrice(ctx context.Context) (sdk.DecCoin, error) {
+	static, err := sdk.ParseDecCoin(c.gasPrices)
+	if err != nil {
+		return sdk.DecCoin{}, err
+	}
+	if c.feeMode == "" || c.feeMode == FeeModeStatic {
+		return static, nil
+	}
+	var maxPrice *sdk.DecCoin
+	if c.maxGasPrice != "" {
+		price, err := sdk.ParseDecCoin(c.maxGasPrice)
+		if err != nil {
+			return sdk.DecCoin{}, err
+		}
+		if price.Denom != static.Denom {
+			return sdk.DecCoin{}, fmt.Errorf("max gas price %s is not in the gas price denom %s", price, static.Denom)
+		}
+		maxPrice = &price
+	}
+
+	c.gasPriceCache.mu.Lock()
+	defer c.gasPriceCache.mu.Unlock()
+	if !c.gasPriceCache.fetched.IsZero() && time.Since(c.gasPriceCache.fetched) < c.feeCacheTTL {
+		return c.gasPriceCache.price, nil
+	}
+
+	var price sdk.DecCoin
+	switch c.feeMode {
+	case FeeModeNode:
+		price, err = c.nodeGasPrice(ctx, static.Denom)
+	case FeeModeFeeMarket:
+		price, err = c.feeMarketGasPrice(ctx, static.Denom)
+	case FeeModeAuto:
+		price, err = c.feeMarketGasPrice(ctx, static.Denom)
+		if err != nil {
+			price, err = c.nodeGasPrice(ctx, static.Denom)
+		}
+	default:
+		return sdk.DecCoin{}, fmt.Errorf("unknown fee mode %q", c.feeMode)
+	}
+	if err != nil || price.Denom != static.Denom {
+		price = static
+	}
+	if maxPrice != nil && price.Amount.GT(maxPrice.Amount) {
+		price = *maxPrice
+	}
+	c.gasPriceCache.price = price
+	c.gasPriceCache.fetched = time.Now()
+	return price, nil
+}
+
+// fee returns the fee for the gas at the current gas price
+func (c *Client) fee(ctx context.Context, gas uint64) (sdk.Coin, error) {
+	price, err := c.gasPrice(ctx)
+	if err != nil {
+		return sdk.Coin{}, err
+	}
+	amount := price.Amount.MulInt(sdkmath.NewIntFromUint64(gas)).Ceil().TruncateInt()
+	return sdk.NewCoin(price.Denom, amount), nil
+}
+
+func (c *Client) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &statusError{url: url, code: resp.StatusCode, body: body}
+	}
+	return json.Unmarshal(body, v)
+}
+
+// statusError is the error of a query that didn't return 200
+type statusError struct {
+	url  string
+	code int
+	body []byte
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("request to %s failed with status %d: %s", e.url, e.code, e.body)
+}
+
+// isNotFound returns whether the query failed because the resource doesn't exist
+func isNotFound(err error) bool {
+	var statusErr *statusError
+	return errors.As(err, &statusErr) && statusErr.code == http.StatusNotFound
+}
+
+// nodeGasPrice returns the minimum gas price of the node for the denom
+func (c *Client) nodeGasPrice(ctx context.Context, denom string) (sdk.DecCoin, error) {
+	var config NodeConfigResponse
+	err := c.getJSON(ctx, fmt.Sprintf("%s/cosmos/base/node/v1beta1/config", c.apiEndpoint), &config)
+	if err != nil {
+		return sdk.DecCoin{}, err
+	}
+	prices, err := sdk.ParseDecCoins(config.MinimumGasPrice)
+	if err != nil {
+		return sdk.DecCoin{}, err
+	}
+	for _, price := range prices {
+		if price.Denom == denom {
+			return price, nil
+		}
+	}
+	// the node accepts any fee in the denom
+	return sdk.NewDecCoinFromDec(denom, sdkmath.LegacyZeroDec()), nil
+}
+
+// feeMarketGasPrice returns the gas price of the feemarket module for the denom
+func (c *Client) feeMarketGasPrice(ctx context.Context, denom string) (sdk.DecCoin, error) {
+	var gasPrice FeeMarketGasPriceResponse
+	err := c.getJSON(ctx, fmt.Sprintf("%s/feemarket/v1/gas_price/%s", c.apiEndpoint, denom), &gasPrice)
+	if err != nil {
+		return sdk.DecCoin{}, err
+	}
+	amount, err := sdkmath.LegacyNewDecFromStr(gasPrice.Price.Amount)
+	if err != nil {
+		return sdk.DecCoin{}, err
+	}
+	return sdk.NewDecCoinFromDec(gasPrice.Price.Denom, amount), nil
+}
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	transfertypes "github.com/cosmos/ibc-go/v8/modules/apps/transfer/types"
+	clienttypes "github.com/cosmos/ibc-go/v8/modules/core/02-client/types"
+	channeltypes "github.com/cosmos/ibc-go/v8/modules/core/04-channel/types"
+)
+
+const (
+	// PacketPending is a packet that was not acknowledged nor timed out yet
+	PacketPending = "pending"
+	// PacketAcknowledged is a packet received by the counterparty chain
+	PacketAcknowledged = "acknowledged"
+	// PacketFailed is a packet the counterparty chain acknowledged with an error, the tokens are refunded
+	PacketFailed = "failed"
+	// PacketTimedOut is a packet that was not received before its timeout, the tokens are refunded once
+	// the timeout is relayed
+	PacketTimedOut = "timeout"
+	// PacketUnknown is a packet whose acknowledgement or timeout was relayed but neither transaction can
+	// be found, for example when the node doesn't index transactions
+	PacketUnknown = "unknown"
+)
+
+// ErrTxNotFound is returned when a transaction is not indexed by the node yet
+var ErrTxNotFound = errors.New("tx not found")
+
+// IBCTransfer sends the coins to an address of the counterparty chain with one MsgTransfer per coin
+func (c *Client) IBCTransfer(ctx context.Context, sourcePort, sourceChannel, receiver, amount string, timeout time.Duration) (*TxResult, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Second+c.confirmTimeout)
+	defer cancel()
+	coins, err := ParseCoins(amount)
+	if err != nil {
+		return nil, err
+	}
+	w, err := c.acquire(timeoutCtx)
+	if err != nil {
+		return nil, err
+	}
+	timeoutTimestamp := uint64(time.Now().Add(timeout).UnixNano())
+	msgs := make([]sdk.Msg, 0, len(coins))
+	for _, coin := range coins {
+		msgs = append(msgs, transfertypes.NewMsgTransfer(sourcePort, sourceChannel, coin, w.address, receiver, clienttypes.ZeroHeight(), timeoutTimestamp, ""))
+	}
+	return c.broadcast(timeoutCtx, w, c.txFactory, c.txConfig, msgs...)
+}
+
+// PacketSequences returns the sequences of the packets sent by a transaction, ErrTxNotFound is
+// returned until the transaction is included in a block
+func (c *Client) PacketSequences(ctx context.Context, txHash string) ([]uint64, error) {
+	var tx GetTxResponse
+	err := c.getJSON(ctx, fmt.Sprintf("%s/cosmos/tx/v1beta1/txs/%s", c.apiEndpoint, txHash), &tx)
+	if isNotFound(err) {
+		return nil, ErrTxNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tx.TxResponse.Code != 0 {
+		return nil, fmt.Errorf("%w: %d, log: %s", ErrTxFailed, tx.TxResponse.Code, tx.TxResponse.RawLog)
+	}
+	var sequences []uint64
+	for _, event := range tx.TxResponse.Events {
+		if event.Type != channeltypes.EventTypeSendPacket {
+			continue
+		}
+		sequence, err := strconv.ParseUint(event.Attribute(channeltypes.AttributeKeySequence), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid packet sequence: %w", err)
+		}
+		sequences = append(sequences, sequence)
+	}
+	return sequences, nil
+}
+
+// PacketStatus returns the status of a packet sent to the receiver, see the Packet constants. The packet
+// commitment is deleted once the acknowledgement or the timeout is relayed back to the faucet chain
+func (c *Client) PacketStatus(ctx context.Context, sourcePort, sourceChannel, receiver string, sequence uint64) (string, error) {
+	var commitment PacketCommitmentResponse
+	err := c.getJSON(ctx, fmt.Sprintf("%s/ibc/core/channel/v1/channels/%s/ports/%s/packet_commitments/%d", c.apiEndpoint, sourceChannel, sourcePort, sequence), &commitment)
+	if err == nil {
+		return PacketPending, nil
+	}
+	if !isNotFound(err) {
+		return "", err
+	}
+
+	packetQuery := func(eventType string) string {
+		return fmt.Sprintf("%s.%s='%d' AND %s.%s='%s'", eventType, channeltypes.AttributeKeySequence, sequence, eventType, channeltypes.AttributeKeySrcChannel, sourceChannel)
+	}
+	txs, err := c.searchTxs(ctx, packetQuery(channeltypes.EventTypeTimeoutPacket))
+	if err != nil {
+		return "", err
+	}
+	if len(txs.TxResponses) > 0 {
+		return PacketTimedOut, nil
+	}
+	txs, err = c.searchTxs(ctx, packetQuery(channeltypes.EventTypeAcknowledgePacket))
+	if err != nil {
+		return "", err
+	}
+	if len(txs.TxResponses) == 0 {
+		return PacketUnknown, nil
+	}
+	// a relayer can acknowledge several packets in one transaction, the error is matched by receiver
+	for _, tx := range txs.TxResponses {
+		for _, event := range tx.Events {
+			if event.Type == transfertypes.EventTypePacket && event.Attribute(transfertypes.AttributeKeyReceiver) == receiver &&
+				event.Attribute(transfertypes.AttributeKeyAckError) != "" {
+				return PacketFailed, nil
+			}
+		}
+	}
+	return PacketAcknowledged, nil
+}
+
+func (c *Client) searchTxs(ctx context.Context, query string) (SearchTxsResponse, error) {
+	var txs SearchTxsResponse
+	err := c.getJSON(ctx, fmt.Sprintf("%s/cosmos/tx/v1beta1/txs?query=%s&pagination.limit=10", c.apiEndpoint, url.QueryEscape(query)), &txs)
+	return txs, err
+}
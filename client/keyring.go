@@ -3,64 +3,144 @@ package client
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
-	"strconv"
+	"time"
 
-	sdkmath "cosmossdk.io/math"
+	"cosmossdk.io/core/address"
+	"cosmossdk.io/x/feegrant"
+	txsigning "cosmossdk.io/x/tx/signing"
 	"github.com/cometbft/cometbft/crypto/tmhash"
+	rpchttp "github.com/cometbft/cometbft/rpc/client/http"
 	"github.com/cosmos/cosmos-sdk/client"
 	"github.com/cosmos/cosmos-sdk/client/tx"
 	"github.com/cosmos/cosmos-sdk/codec"
+	addresscodec "github.com/cosmos/cosmos-sdk/codec/address"
 	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
 	cryptocodec "github.com/cosmos/cosmos-sdk/crypto/codec"
 	"github.com/cosmos/cosmos-sdk/crypto/hd"
 	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/cosmos/cosmos-sdk/types/tx/signing"
 	authtx "github.com/cosmos/cosmos-sdk/x/auth/tx"
+	"github.com/cosmos/cosmos-sdk/x/authz"
 	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/cosmos/gogoproto/proto"
+	transfertypes "github.com/cosmos/ibc-go/v8/modules/apps/transfer/types"
+	"github.com/public-awesome/faucet/client/ethsecp256k1"
+	"github.com/public-awesome/faucet/client/wasm"
+)
+
+const (
+	// KeyAlgoSecp256k1 is the key algorithm of most cosmos chains
+	KeyAlgoSecp256k1 = string(hd.Secp256k1Type)
+	// KeyAlgoEthSecp256k1 is the key algorithm of ethermint based chains such as Evmos and Cronos
+	KeyAlgoEthSecp256k1 = ethsecp256k1.KeyType
 )
 
 func setupKeyring(cdc codec.Codec) keyring.Keyring {
-	k := keyring.NewInMemory(cdc)
+	k := keyring.NewInMemory(cdc, func(options *keyring.Options) {
+		options.SupportedAlgos = keyring.SigningAlgoList{hd.Secp256k1, ethsecp256k1.Algo}
+	})
 	return k
 }
 
-func txConfig() (client.TxConfig, codec.Codec) {
-	registry := codectypes.NewInterfaceRegistry()
+// ValidKeyAlgo reports whether the wallets can use the key algorithm, see the KeyAlgo constants
+func ValidKeyAlgo(algo string) bool {
+	_, err := signingAlgo(algo)
+	return err == nil
+}
+
+// signingAlgo returns the keyring algorithm of the key algorithm
+func signingAlgo(algo string) (keyring.SignatureAlgo, error) {
+	return keyring.NewSigningAlgoFromString(algo, keyring.SigningAlgoList{hd.Secp256k1, ethsecp256k1.Algo})
+}
+
+// txConfig builds a registry with the address codec of the chain so several clients with different
+// prefixes can live in one process without the global sdk config
+func txConfig(addressCodec address.Codec, accountPrefix string) (client.TxConfig, codec.Codec, error) {
+	registry, err := codectypes.NewInterfaceRegistryWithOptions(codectypes.InterfaceRegistryOptions{
+		ProtoFiles: proto.HybridResolver,
+		SigningOptions: txsigning.Options{
+			AddressCodec:          addressCodec,
+			ValidatorAddressCodec: addresscodec.NewBech32Codec(accountPrefix + "valoper"),
+		},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
 	cryptocodec.RegisterInterfaces(registry)
+	registry.RegisterImplementations((*cryptotypes.PubKey)(nil), &ethsecp256k1.PubKey{})
+	registry.RegisterImplementations((*cryptotypes.PrivKey)(nil), &ethsecp256k1.PrivKey{})
 	banktypes.RegisterInterfaces(registry)
+	registry.RegisterImplementations((*sdk.Msg)(nil), &wasm.MsgExecuteContract{})
+	transfertypes.RegisterInterfaces(registry)
+	feegrant.RegisterInterfaces(registry)
+	authz.RegisterInterfaces(registry)
 	cdc := codec.NewProtoCodec(registry)
-	return authtx.NewTxConfig(cdc, authtx.DefaultSignModes), cdc
+	return authtx.NewTxConfig(cdc, authtx.DefaultSignModes), cdc, nil
 }
 
-func (c *Client) setupFactory() {
-	sdk.GetConfig().SetBech32PrefixForAccount(c.accountPrefix, c.accountPrefix+"pub")
-
-	txConfig, cdc := txConfig()
+func (c *Client) setupFactory() error {
+	c.addressCodec = addresscodec.NewBech32Codec(c.accountPrefix)
+	txConfig, cdc, err := txConfig(c.addressCodec, c.accountPrefix)
+	if err != nil {
+		return err
+	}
 	keybase := setupKeyring(cdc)
-	path := hd.CreateHDPath(c.coinType, 0, 0).String()
-	r, err := keybase.NewAccount("faucet-test", c.faucetMnemonics, "", path, hd.Secp256k1)
+	algo, err := signingAlgo(c.keyAlgo)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	if c.accounts == 0 {
+		c.accounts = 1
+	}
+	for i := uint32(0); i < c.accounts; i++ {
+		w, err := c.newWallet(keybase, algo, fmt.Sprintf("faucet-%d", i), c.faucetMnemonics, i)
+		if err != nil {
+			return err
+		}
+		c.wallets = append(c.wallets, w)
+	}
+	if c.treasuryMnemonics != "" {
+		c.treasury, err = c.newWallet(keybase, algo, "treasury", c.treasuryMnemonics, 0)
+	} else if c.treasuryIndex != nil {
+		if *c.treasuryIndex < c.accounts {
+			return fmt.Errorf("treasury index %d overlaps the hot wallets, it must be at least %d", *c.treasuryIndex, c.accounts)
+		}
+		c.treasury, err = c.newWallet(keybase, algo, "treasury", c.faucetMnemonics, *c.treasuryIndex)
 	}
-	pubkey, err := r.GetPubKey()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	accAddr := sdk.AccAddress(pubkey.Address())
-	c.account = accAddr.String()
 	factory := tx.Factory{}.WithKeybase(keybase).
 		// WithGasPrices(c.gasPrices).
 		WithChainID(c.chainID).
 		WithSignMode(signing.SignMode_SIGN_MODE_DIRECT).
-		WithTxConfig(txConfig).
-		WithFromName("faucet-test")
+		WithTxConfig(txConfig)
 	c.txFactory = factory
 	c.txConfig = txConfig
+	return nil
+}
+
+func (c *Client) newWallet(keybase keyring.Keyring, algo keyring.SignatureAlgo, name, mnemonics string, index uint32) (*wallet, error) {
+	path := hd.CreateHDPath(c.coinType, 0, index).String()
+	r, err := keybase.NewAccount(name, mnemonics, "", path, algo)
+	if err != nil {
+		return nil, fmt.Errorf("%s wallet: %w", name, err)
+	}
+	pubkey, err := r.GetPubKey()
+	if err != nil {
+		return nil, fmt.Errorf("%s wallet: %w", name, err)
+	}
+	address, err := c.addressCodec.BytesToString(pubkey.Address())
+	if err != nil {
+		return nil, fmt.Errorf("%s wallet: %w", name, err)
+	}
+	return &wallet{name: name, address: address}, nil
 }
 
 func (c *Client) getAccountInfo(ctx context.Context, address string) (AccountInfoResponse, error) {
@@ -85,80 +165,236 @@ func (c *Client) getAccountInfo(ctx context.Context, address string) (AccountInf
 	return accountInfo, nil
 }
 
-func (c *Client) transfer(ctx context.Context, factory tx.Factory, txConfig client.TxConfig, to string, amount string) (string, error) {
-	accountInfo, err := c.getAccountInfo(ctx, c.account)
+func (c *Client) transfer(ctx context.Context, factory tx.Factory, txConfig client.TxConfig, to string, amount string) (*TxResult, error) {
+	coins, err := ParseCoins(amount)
+	if err != nil {
+		return nil, err
+	}
+	to, err = c.NormalizeAddress(to)
+	if err != nil {
+		return nil, err
+	}
+	w, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := c.sendMsgs(w.address, to, coins)
+	if err == nil {
+		msgs, err = c.authzMsgs(w.address, msgs)
+	}
+	if err != nil {
+		c.release(w)
+		return nil, err
+	}
+	return c.broadcast(ctx, w, factory, txConfig, msgs...)
+}
+
+func (c *Client) multiTransfer(ctx context.Context, factory tx.Factory, txConfig client.TxConfig, sends []Send) (*TxResult, error) {
+	amounts := make([]sdk.Coins, 0, len(sends))
+	addresses := make([]string, 0, len(sends))
+	for _, send := range sends {
+		coins, err := ParseCoins(send.Amount)
+		if err != nil {
+			return nil, err
+		}
+		address, err := c.NormalizeAddress(send.Address)
+		if err != nil {
+			return nil, err
+		}
+		amounts = append(amounts, coins)
+		addresses = append(addresses, address)
+	}
+	w, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// native and cw20 sends of every recipient go in the same transaction
+	msgs := make([]sdk.Msg, 0, len(sends))
+	for i := range sends {
+		sendMsgs, err := c.sendMsgs(w.address, addresses[i], amounts[i])
+		if err != nil {
+			c.release(w)
+			return nil, err
+		}
+		msgs = append(msgs, sendMsgs...)
+	}
+	msgs, err = c.authzMsgs(w.address, msgs)
 	if err != nil {
-		return "", err
+		c.release(w)
+		return nil, err
 	}
+	return c.broadcast(ctx, w, factory, txConfig, msgs...)
+}
 
-	coins, err := sdk.ParseCoinsNormalized(amount)
+func (c *Client) topUp(ctx context.Context, factory tx.Factory, txConfig client.TxConfig, to string, coins sdk.Coins) (*TxResult, error) {
+	_, err := c.addressCodec.StringToBytes(to)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	toAddr, err := sdk.AccAddressFromBech32(to)
+	msgs, err := c.sendMsgs(c.treasury.address, to, coins)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	msg := banktypes.NewMsgSend(sdk.MustAccAddressFromBech32(c.account), toAddr, coins)
+	c.treasury.sequence.mu.Lock()
+	return c.broadcast(ctx, c.treasury, factory, txConfig, msgs...)
+}
 
-	accountNumber, err := strconv.ParseUint(accountInfo.AccountInfo.AccountNumber, 10, 64)
+// ErrTxFailed is returned when a transaction was rejected by the node or failed in its block
+var ErrTxFailed = errors.New("tx failed")
+
+// TxFailed reports whether a send that returned an error definitely transferred nothing: the transaction
+// was never broadcasted, the node rejected it or it failed in its block. After other errors such as a
+// broadcast or confirmation timeout the transaction may still be included
+func TxFailed(result *TxResult, err error) bool {
+	return err != nil && (result == nil || result.TxHash == "" || errors.Is(err, ErrTxFailed))
+}
+
+type broadcastHookKey struct{}
+
+// WithBroadcastHook returns a context that calls hook with the hash of every transaction signed with it
+// before the transaction is broadcasted, the transaction is not broadcasted if the hook fails
+func WithBroadcastHook(ctx context.Context, hook func(txHash string) error) context.Context {
+	return context.WithValue(ctx, broadcastHookKey{}, hook)
+}
+
+// broadcast signs the messages with the wallet's cached sequence and broadcasts them, retrying once
+// with a resynced sequence when the node reports a sequence mismatch. The wallet must be acquired
+// and is released once the transaction is in the mempool.
+func (c *Client) broadcast(ctx context.Context, w *wallet, factory tx.Factory, txConfig client.TxConfig, msgs ...sdk.Msg) (*TxResult, error) {
+	node, err := client.NewClientFromNode(c.rpcEndpoint)
 	if err != nil {
-		return "", err
+		c.release(w)
+		return nil, err
+	}
+
+	var (
+		result  *TxResult
+		txBytes []byte
+	)
+	for attempt := 0; ; attempt++ {
+		result, txBytes, err = c.signAndBroadcast(ctx, node, w, factory, txConfig, msgs...)
+		if err == nil || attempt > 0 || result == nil || !isSequenceMismatch(result.RawLog) {
+			break
+		}
+	}
+	c.release(w)
+	if err != nil || c.confirmTimeout == 0 {
+		return result, err
 	}
-	sequence, err := strconv.ParseUint(accountInfo.AccountInfo.Sequence, 10, 64)
+	return c.waitForTx(ctx, node, txBytes, result)
+}
+
+// signAndBroadcast must be called with the wallet acquired
+func (c *Client) signAndBroadcast(ctx context.Context, node *rpchttp.HTTP, w *wallet, factory tx.Factory, txConfig client.TxConfig, msgs ...sdk.Msg) (*TxResult, []byte, error) {
+	err := w.sequence.load(ctx, func(ctx context.Context) (AccountInfoResponse, error) {
+		return c.getAccountInfo(ctx, w.address)
+	})
 	if err != nil {
-		return "", err
+		return nil, nil, err
 	}
-	factory.SimulateAndExecute()
 
-	fees, err := sdk.ParseCoinNormalized(c.gasPrices)
+	factory = factory.WithAccountNumber(w.sequence.accountNumber).WithSequence(w.sequence.sequence).WithFromName(w.name)
+	gas := c.estimateGas(ctx, factory, msgs...)
+	fees, err := c.fee(ctx, gas)
 	if err != nil {
-		return "", err
+		return nil, nil, err
 	}
-	fees.Amount = fees.Amount.Mul(sdkmath.NewInt(c.gasAmount))
-	factory = factory.WithGas(uint64(c.gasAmount)).WithAccountNumber(accountNumber).WithSequence(sequence).WithFees(fees.String())
-	txb, err := factory.BuildUnsignedTx(msg)
+	factory = factory.WithGas(gas).WithFees(fees.String())
+	txb, err := factory.BuildUnsignedTx(msgs...)
 	if err != nil {
-		return "", err
+		return nil, nil, err
 	}
-	err = tx.Sign(ctx, factory, "faucet-test", txb, false)
+	err = tx.Sign(ctx, factory, w.name, txb, false)
 	if err != nil {
-		return "", err
+		return nil, nil, err
 	}
 	txBytes, err := txConfig.TxEncoder()(txb.GetTx())
 	if err != nil {
-		return "", err
+		return nil, nil, err
 	}
 
-	// tx, err := txConfig.TxDecoder()(txBytes)
-	// if err != nil {
-	// 	log.Fatal(err)
-	// }
-
 	txHash := fmt.Sprintf("%X", tmhash.Sum(txBytes))
-	// jxJsonBytes, err := txConfig.TxJSONEncoder()(tx)
-	// if err != nil {
-	// 	log.Fatal(err)
-	// }
+	result := &TxResult{TxHash: txHash}
+	if hook, ok := ctx.Value(broadcastHookKey{}).(func(string) error); ok {
+		err = hook(txHash)
+		if err != nil {
+			return nil, txBytes, fmt.Errorf("failed to record tx %s: %w", txHash, err)
+		}
+	}
 
-	node, err := client.NewClientFromNode(c.rpcEndpoint)
+	res, err := node.BroadcastTxSync(ctx, txBytes)
 	if err != nil {
-		return "", err
+		// the tx may or may not have reached the mempool, resync to be safe
+		w.sequence.reset()
+		return result, txBytes, fmt.Errorf("failed to broadcast tx: %w", err)
 	}
-	res, err := node.BroadcastTxAsync(context.Background(), txBytes)
-	if err != nil {
-		log := ""
-		if res != nil && res.Log != "" {
-			log = res.Log
+	if res.Code != 0 {
+		result.Code = res.Code
+		result.RawLog = res.Log
+		if isSequenceMismatch(res.Log) {
+			w.sequence.resync(res.Log)
 		}
-		return txHash, fmt.Errorf("failed to broadcast tx: %w, log: %s", err, log)
+		return result, txBytes, fmt.Errorf("%w: %d, log: %s", ErrTxFailed, res.Code, res.Log)
 	}
-	if res.Code != 0 {
-		return txHash, fmt.Errorf("tx failed: %d, log: %s", res.Code, res.Log)
+	w.sequence.increment()
+	return result, txBytes, nil
+}
+
+// waitForTx polls the node until the transaction is included in a block or the confirm timeout passes
+func (c *Client) waitForTx(ctx context.Context, node *rpchttp.HTTP, txBytes []byte, result *TxResult) (*TxResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.confirmTimeout)
+	defer cancel()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return result, fmt.Errorf("tx %s was not confirmed after %s", result.TxHash, c.confirmTimeout)
+		case <-ticker.C:
+			res, err := node.Tx(ctx, tmhash.Sum(txBytes), false)
+			if err != nil {
+				// the node returns an error until the tx is indexed
+				continue
+			}
+			result.Confirmed = true
+			result.Height = res.Height
+			result.Code = res.TxResult.Code
+			result.GasUsed = res.TxResult.GasUsed
+			result.RawLog = res.TxResult.Log
+			if res.TxResult.Code != 0 {
+				return result, fmt.Errorf("%w at height %d: %d, log: %s", ErrTxFailed, res.Height, res.TxResult.Code, res.TxResult.Log)
+			}
+			return result, nil
+		}
+	}
+}
+
+// TxStatus returns the result of an included transaction, ErrTxNotFound is returned until the transaction
+// is included in a block and ErrTxFailed if it failed in its block
+func (c *Client) TxStatus(ctx context.Context, txHash string) (*TxResult, error) {
+	var tx GetTxResponse
+	err := c.getJSON(ctx, fmt.Sprintf("%s/cosmos/tx/v1beta1/txs/%s", c.apiEndpoint, txHash), &tx)
+	if isNotFound(err) {
+		return nil, ErrTxNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := &TxResult{
+		TxHash:    txHash,
+		Confirmed: true,
+		Height:    tx.TxResponse.Height,
+		Code:      tx.TxResponse.Code,
+		GasUsed:   tx.TxResponse.GasUsed,
+		RawLog:    tx.TxResponse.RawLog,
+	}
+	if result.Code != 0 {
+		return result, fmt.Errorf("%w at height %d: %d, log: %s", ErrTxFailed, result.Height, result.Code, result.RawLog)
 	}
-	return txHash, nil
+	return result, nil
 }
 
+// FaucetAddress returns the address of the first hot wallet
 func (c *Client) FaucetAddress() string {
-	return c.account
+	return c.wallets[0].address
 }
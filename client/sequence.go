@@ -0,0 +1,73 @@
+package client
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// sequenceMismatchRegexp extracts the expected sequence from an "account sequence mismatch, expected 5, got 4" error
+var sequenceMismatchRegexp = regexp.MustCompile(`account sequence mismatch, expected (\d+)`)
+
+// sequenceTracker caches the account number and sequence of an account so transactions
+// can be signed back to back without waiting for the previous one to be committed.
+// The mutex must be held while a transaction is signed and broadcasted.
+type sequenceTracker struct {
+	mu            sync.Mutex
+	loaded        bool
+	accountNumber uint64
+	sequence      uint64
+}
+
+// load fetches the account number and sequence from the chain if they are not cached, must be called with the lock held
+func (s *sequenceTracker) load(ctx context.Context, fetch func(ctx context.Context) (AccountInfoResponse, error)) error {
+	if s.loaded {
+		return nil
+	}
+	accountInfo, err := fetch(ctx)
+	if err != nil {
+		return err
+	}
+	accountNumber, err := strconv.ParseUint(accountInfo.AccountInfo.AccountNumber, 10, 64)
+	if err != nil {
+		return err
+	}
+	sequence, err := strconv.ParseUint(accountInfo.AccountInfo.Sequence, 10, 64)
+	if err != nil {
+		return err
+	}
+	s.accountNumber = accountNumber
+	s.sequence = sequence
+	s.loaded = true
+	return nil
+}
+
+// increment is called after a transaction passed CheckTx
+func (s *sequenceTracker) increment() {
+	s.sequence++
+}
+
+// reset drops the cached values so the next transaction resyncs them from the chain
+func (s *sequenceTracker) reset() {
+	s.loaded = false
+}
+
+// resync handles a sequence mismatch error, using the expected sequence reported by the node
+// when it is available and otherwise resyncing from the chain on the next transaction
+func (s *sequenceTracker) resync(log string) {
+	matches := sequenceMismatchRegexp.FindStringSubmatch(log)
+	if len(matches) == 2 {
+		sequence, err := strconv.ParseUint(matches[1], 10, 64)
+		if err == nil && s.loaded {
+			s.sequence = sequence
+			return
+		}
+	}
+	s.reset()
+}
+
+func isSequenceMismatch(log string) bool {
+	return strings.Contains(log, "account sequence mismatch")
+}
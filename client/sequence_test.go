@@ -0,0 +1,33 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSequenceTracker(t *testing.T) {
+	calls := 0
+	fetch := func(ctx context.Context) (AccountInfoResponse, error) {
+		calls++
+		return AccountInfoResponse{AccountInfo: AccountInfo{AccountNumber: "7", Sequence: "10"}}, nil
+	}
+	var s sequenceTracker
+	assert.NoError(t, s.load(context.Background(), fetch))
+	s.increment()
+	assert.NoError(t, s.load(context.Background(), fetch))
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, uint64(7), s.accountNumber)
+	assert.Equal(t, uint64(11), s.sequence)
+
+	s.resync("account sequence mismatch, expected 15, got 11: incorrect account sequence")
+	assert.True(t, s.loaded)
+	assert.Equal(t, uint64(15), s.sequence)
+
+	s.resync("account sequence mismatch")
+	assert.False(t, s.loaded)
+	assert.NoError(t, s.load(context.Background(), fetch))
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, uint64(10), s.sequence)
+}
@@ -0,0 +1,102 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/cosmos/cosmos-sdk/client/tx"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// simulate returns the gas used by the messages as reported by the tx service simulation
+func (c *Client) simulate(ctx context.Context, factory tx.Factory, msgs ...sdk.Msg) (uint64, error) {
+	txBytes, err := factory.WithSimulateAndExecute(true).BuildSimTx(msgs...)
+	if err != nil {
+		return 0, err
+	}
+	body, err := json.Marshal(SimulateRequest{TxBytes: base64.StdEncoding.EncodeToString(txBytes)})
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/cosmos/tx/v1beta1/simulate", c.apiEndpoint), bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("failed to simulate tx: %s", respBody)
+	}
+	var simulation SimulateResponse
+	err = json.Unmarshal(respBody, &simulation)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(simulation.GasInfo.GasUsed, 10, 64)
+}
+
+// blockGasCache holds the gas limit of a block once it was queried
+type blockGasCache struct {
+	mu      sync.Mutex
+	limit   uint64
+	fetched bool
+}
+
+// blockGasLimit returns the gas limit of a block from the consensus params, zero if blocks have no limit.
+// The limit is queried once, a failed query is retried with the next transaction
+func (c *Client) blockGasLimit(ctx context.Context) (uint64, error) {
+	c.blockGas.mu.Lock()
+	defer c.blockGas.mu.Unlock()
+	if c.blockGas.fetched {
+		return c.blockGas.limit, nil
+	}
+	var params ConsensusParamsResponse
+	err := c.getJSON(ctx, fmt.Sprintf("%s/cosmos/consensus/v1/params", c.apiEndpoint), &params)
+	if err != nil {
+		return 0, err
+	}
+	maxGas, err := strconv.ParseInt(params.Params.Block.MaxGas, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	// -1 means blocks have no gas limit
+	if maxGas > 0 {
+		c.blockGas.limit = uint64(maxGas)
+	}
+	c.blockGas.fetched = true
+	return c.blockGas.limit, nil
+}
+
+// estimateGas simulates the messages and applies the gas adjustment, falling back to the
+// fixed gas amount per message when simulation is disabled or fails. The estimate is capped
+// by the block gas limit so large batches can still be included
+func (c *Client) estimateGas(ctx context.Context, factory tx.Factory, msgs ...sdk.Msg) uint64 {
+	gas := uint64(c.gasAmount) * uint64(len(msgs))
+	if c.simulateGas {
+		gasUsed, err := c.simulate(ctx, factory, msgs...)
+		if err == nil {
+			gas = uint64(math.Ceil(float64(gasUsed) * c.gasAdjustment))
+		}
+	}
+	limit, err := c.blockGasLimit(ctx)
+	if err == nil && limit > 0 && gas > limit {
+		return limit
+	}
+	return gas
+}
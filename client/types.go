@@ -1,5 +1,12 @@
 package client
 
+import (
+	"encoding/json"
+
+	sdkmath "cosmossdk.io/math"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
 type AccountInfoResponse struct {
 	AccountInfo AccountInfo `json:"info"`
 }
@@ -13,3 +20,151 @@ type AccountInfo struct {
 	AccountNumber string `json:"account_number"`
 	Sequence      string `json:"sequence"`
 }
+
+// TxResult is the outcome of a broadcasted transaction, Height, Code, GasUsed and RawLog
+// are only set when the transaction was confirmed
+type TxResult struct {
+	TxHash    string `json:"tx_hash"`
+	Confirmed bool   `json:"confirmed"`
+	Height    int64  `json:"height"`
+	Code      uint32 `json:"code"`
+	GasUsed   int64  `json:"gas_used"`
+	RawLog    string `json:"raw_log"`
+}
+
+// Send is a single recipient of a multi send
+type Send struct {
+	Address string
+	Amount  string
+}
+
+type SimulateRequest struct {
+	TxBytes string `json:"tx_bytes"`
+}
+
+type GasInfo struct {
+	GasWanted string `json:"gas_wanted"`
+	GasUsed   string `json:"gas_used"`
+}
+
+type SimulateResponse struct {
+	GasInfo GasInfo `json:"gas_info"`
+}
+
+type ConsensusParamsResponse struct {
+	Params struct {
+		Block struct {
+			MaxGas string `json:"max_gas"`
+		} `json:"block"`
+	} `json:"params"`
+}
+
+type NodeConfigResponse struct {
+	MinimumGasPrice string `json:"minimum_gas_price"`
+}
+
+type FeeMarketGasPrice struct {
+	Denom  string `json:"denom"`
+	Amount string `json:"amount"`
+}
+
+type FeeMarketGasPriceResponse struct {
+	Price FeeMarketGasPrice `json:"price"`
+}
+
+type BalancesResponse struct {
+	Balances sdk.Coins `json:"balances"`
+}
+
+type WalletBalance struct {
+	Address  string    `json:"address"`
+	Balances sdk.Coins `json:"balances"`
+	ChainID  string    `json:"chain_id"`
+	// AuthzGranter sends the coins on behalf of the wallet, AuthzLimit is what's left of its send authorization
+	AuthzGranter string    `json:"authz_granter,omitempty"`
+	AuthzLimit   sdk.Coins `json:"authz_limit,omitempty"`
+}
+
+type NodeInfoResponse struct {
+	DefaultNodeInfo struct {
+		Network string `json:"network"`
+	} `json:"default_node_info"`
+}
+
+type SupplyOfResponse struct {
+	Amount sdk.Coin `json:"amount"`
+}
+
+type AccountResponse struct {
+	Account json.RawMessage `json:"account"`
+}
+
+// CW20BalanceResponse is the response of the balance query of a CW20 contract
+type CW20BalanceResponse struct {
+	Data struct {
+		Balance sdkmath.Int `json:"balance"`
+	} `json:"data"`
+}
+
+// ContractInfoResponse is the response of the contract info query of the wasm module
+type ContractInfoResponse struct {
+	Address      string          `json:"address"`
+	ContractInfo json.RawMessage `json:"contract_info"`
+}
+
+// TxEvent is an event emitted by a transaction
+type TxEvent struct {
+	Type       string `json:"type"`
+	Attributes []struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	} `json:"attributes"`
+}
+
+// Attribute returns the value of the attribute or an empty string
+func (e TxEvent) Attribute(key string) string {
+	for _, attribute := range e.Attributes {
+		if attribute.Key == key {
+			return attribute.Value
+		}
+	}
+	return ""
+}
+
+// TxResponse is the result of a transaction included in a block
+type TxResponse struct {
+	TxHash  string    `json:"txhash"`
+	Height  int64     `json:"height,string"`
+	Code    uint32    `json:"code"`
+	RawLog  string    `json:"raw_log"`
+	GasUsed int64     `json:"gas_used,string"`
+	Events  []TxEvent `json:"events"`
+}
+
+type GetTxResponse struct {
+	TxResponse TxResponse `json:"tx_response"`
+}
+
+type SearchTxsResponse struct {
+	TxResponses []TxResponse `json:"tx_responses"`
+}
+
+type PacketCommitmentResponse struct {
+	Commitment string `json:"commitment"`
+}
+
+type FeeAllowancesResponse struct {
+	Allowances []struct {
+		Granter string `json:"granter"`
+		Grantee string `json:"grantee"`
+	} `json:"allowances"`
+}
+
+type AuthzGrantsResponse struct {
+	Grants []struct {
+		Authorization struct {
+			Type       string    `json:"@type"`
+			SpendLimit sdk.Coins `json:"spend_limit"`
+		} `json:"authorization"`
+	} `json:"grants"`
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// Verify checks that the chain id matches the node, that every denom has a supply or CW20 contract,
+// that the faucet accounts exist and that the wallets have a send authorization in authz mode, every
+// failed check is reported
+func (c *Client) Verify(ctx context.Context, denoms []string) error {
+	var errs []error
+	var nodeInfo NodeInfoResponse
+	err := c.getJSON(ctx, fmt.Sprintf("%s/cosmos/base/tendermint/v1beta1/node_info", c.apiEndpoint), &nodeInfo)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error querying node info: %w", err))
+	} else if nodeInfo.DefaultNodeInfo.Network != c.chainID {
+		errs = append(errs, fmt.Errorf("chain id %q doesn't match the node chain id %q", c.chainID, nodeInfo.DefaultNodeInfo.Network))
+	}
+
+	for _, denom := range denoms {
+		if contract, ok := CW20Contract(denom); ok {
+			var info ContractInfoResponse
+			err := c.getJSON(ctx, fmt.Sprintf("%s/cosmwasm/wasm/v1/contract/%s", c.apiEndpoint, contract), &info)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("cw20 contract %s doesn't exist on chain: %w", contract, err))
+			}
+			continue
+		}
+		var supply SupplyOfResponse
+		err := c.getJSON(ctx, fmt.Sprintf("%s/cosmos/bank/v1beta1/supply/by_denom?denom=%s", c.apiEndpoint, url.QueryEscape(denom)), &supply)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error querying supply of %s: %w", denom, err))
+		} else if supply.Amount.Amount.IsNil() || !supply.Amount.Amount.IsPositive() {
+			errs = append(errs, fmt.Errorf("denom %s has no supply on chain", denom))
+		}
+	}
+
+	addresses := c.Addresses()
+	if c.treasury != nil {
+		addresses = append(addresses, c.treasury.address)
+	}
+	if c.authzGranter != "" {
+		addresses = append(addresses, c.authzGranter)
+		for _, address := range c.Addresses() {
+			limit, err := c.AuthzLimit(ctx, address)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("error querying the send authorization of %s: %w", address, err))
+			} else if limit.IsZero() {
+				errs = append(errs, fmt.Errorf("%s has no send authorization from %s", address, c.authzGranter))
+			}
+		}
+	}
+	for _, address := range addresses {
+		var account AccountResponse
+		err := c.getJSON(ctx, fmt.Sprintf("%s/cosmos/auth/v1beta1/accounts/%s", c.apiEndpoint, address), &account)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("account %s doesn't exist on chain, it must be funded: %w", address, err))
+		}
+	}
+	return errors.Join(errs...)
+}
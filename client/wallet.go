@@ -0,0 +1,145 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// wallet is a hot wallet derived from the faucet mnemonic, each wallet tracks its own sequence
+// so transactions from different wallets can be broadcasted in parallel
+type wallet struct {
+	name     string
+	address  string
+	sequence sequenceTracker
+}
+
+// releasedChan returns the channel closed by the next release, it must be taken before trying to lock
+// the wallets so a release in between is never missed
+func (c *Client) releasedChan() <-chan struct{} {
+	c.releasedMu.Lock()
+	defer c.releasedMu.Unlock()
+	return c.released
+}
+
+// acquire returns the next free wallet locked for signing, waiting for one to be released if all are busy
+func (c *Client) acquire(ctx context.Context) (*wallet, error) {
+	for {
+		released := c.releasedChan()
+		start := int(c.next.Add(1))
+		for i := range c.wallets {
+			w := c.wallets[(start+i)%len(c.wallets)]
+			if w.sequence.mu.TryLock() {
+				return w, nil
+			}
+		}
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// acquireAddress waits for the wallet with the given address to be free and locks it for signing
+func (c *Client) acquireAddress(ctx context.Context, address string) (*wallet, error) {
+	for _, w := range c.wallets {
+		if w.address != address {
+			continue
+		}
+		for {
+			released := c.releasedChan()
+			if w.sequence.mu.TryLock() {
+				return w, nil
+			}
+			select {
+			case <-released:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	}
+	return nil, fmt.Errorf("%s is not a faucet wallet", address)
+}
+
+// release unlocks the wallet and wakes every waiter, each of them tries to lock a wallet again
+func (c *Client) release(w *wallet) {
+	w.sequence.mu.Unlock()
+	c.releasedMu.Lock()
+	close(c.released)
+	c.released = make(chan struct{})
+	c.releasedMu.Unlock()
+}
+
+// Wallets returns the number of hot wallets
+func (c *Client) Wallets() int {
+	return len(c.wallets)
+}
+
+// Addresses returns the addresses of all hot wallets
+func (c *Client) Addresses() []string {
+	addresses := make([]string, 0, len(c.wallets))
+	for _, w := range c.wallets {
+		addresses = append(addresses, w.address)
+	}
+	return addresses
+}
+
+// Balances returns the bank balances of an address
+func (c *Client) Balances(ctx context.Context, address string) (sdk.Coins, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/cosmos/bank/v1beta1/balances/%s?pagination.limit=1000", c.apiEndpoint, address), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to query balances: %s", body)
+	}
+	var balances BalancesResponse
+	err = json.Unmarshal(body, &balances)
+	if err != nil {
+		return nil, err
+	}
+	return balances.Balances, nil
+}
+
+// WalletBalances returns the balances of every hot wallet, the balances of the CW20 contracts are
+// included as cw20:<contract> coins. In authz mode the remaining limit of each wallet is included
+func (c *Client) WalletBalances(ctx context.Context, cw20Contracts ...string) ([]WalletBalance, error) {
+	balances := make([]WalletBalance, 0, len(c.wallets))
+	for _, w := range c.wallets {
+		coins, err := c.Balances(ctx, w.address)
+		if err != nil {
+			return nil, err
+		}
+		for _, contract := range cw20Contracts {
+			amount, err := c.CW20Balance(ctx, contract, w.address)
+			if err != nil {
+				return nil, fmt.Errorf("failed to query cw20 balance of %s: %w", contract, err)
+			}
+			coins = coins.Add(sdk.NewCoin(CW20Prefix+contract, amount))
+		}
+		balance := WalletBalance{Address: w.address, Balances: coins, ChainID: c.chainID}
+		if c.authzGranter != "" {
+			balance.AuthzGranter = c.authzGranter
+			balance.AuthzLimit, err = c.AuthzLimit(ctx, w.address)
+			if err != nil {
+				return nil, fmt.Errorf("failed to query the authz limit of %s: %w", w.address, err)
+			}
+		}
+		balances = append(balances, balance)
+	}
+	return balances, nil
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWalletRelease(t *testing.T) {
+	a, b := &wallet{address: "a"}, &wallet{address: "b"}
+	c := &Client{wallets: []*wallet{a, b}, released: make(chan struct{})}
+	a.sequence.mu.Lock()
+	b.sequence.mu.Lock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	acquired := make(chan *wallet, 2)
+	go func() {
+		w, err := c.acquireAddress(ctx, "a")
+		assert.NoError(t, err)
+		acquired <- w
+	}()
+	go func() {
+		w, err := c.acquire(ctx)
+		assert.NoError(t, err)
+		acquired <- w
+	}()
+
+	// releasing b only wakes the generic waiter, the waiter for a must still get a afterwards
+	time.Sleep(50 * time.Millisecond)
+	c.release(b)
+	assert.Equal(t, b, <-acquired)
+	c.release(a)
+	assert.Equal(t, a, <-acquired)
+
+	_, err := c.acquireAddress(ctx, "c")
+	assert.Error(t, err)
+
+	canceled, cancelWait := context.WithCancel(context.Background())
+	cancelWait()
+	_, err = c.acquire(canceled)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,115 @@
+// Package wasm implements the CosmWasm execute message so the faucet can send CW20 tokens without
+// depending on wasmd, which links the wasmvm library through cgo.
+package wasm
+
+import (
+	"bytes"
+	"fmt"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/gogoproto/proto"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// MsgExecuteContractName is the protobuf name used by wasmd
+const MsgExecuteContractName = "cosmwasm.wasm.v1.MsgExecuteContract"
+
+func init() {
+	proto.RegisterType((*MsgExecuteContract)(nil), MsgExecuteContractName)
+}
+
+var _ sdk.Msg = &MsgExecuteContract{}
+
+// MsgExecuteContract executes a contract with a JSON message, it has the same encoding as the wasmd message
+type MsgExecuteContract struct {
+	// Sender is the address executing the contract
+	Sender string
+	// Contract is the address of the contract
+	Contract string
+	// Msg is the JSON encoded message passed to the contract
+	Msg []byte
+	// Funds are the native coins transferred to the contract
+	Funds sdk.Coins
+}
+
+func (m *MsgExecuteContract) Reset()                { *m = MsgExecuteContract{} }
+func (*MsgExecuteContract) ProtoMessage()           {}
+func (*MsgExecuteContract) XXX_MessageName() string { return MsgExecuteContractName }
+
+func (m *MsgExecuteContract) String() string {
+	return fmt.Sprintf("MsgExecuteContract{%s %s %s}", m.Sender, m.Contract, m.Msg)
+}
+
+func (m *MsgExecuteContract) Size() int {
+	b, _ := m.Marshal()
+	return len(b)
+}
+
+// Marshal encodes the fields sender = 1, contract = 2, msg = 3 and funds = 5
+func (m *MsgExecuteContract) Marshal() ([]byte, error) {
+	var b []byte
+	if m.Sender != "" {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendString(b, m.Sender)
+	}
+	if m.Contract != "" {
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendString(b, m.Contract)
+	}
+	if len(m.Msg) > 0 {
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendBytes(b, m.Msg)
+	}
+	for _, coin := range m.Funds {
+		bz, err := coin.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		b = protowire.AppendTag(b, 5, protowire.BytesType)
+		b = protowire.AppendBytes(b, bz)
+	}
+	if b == nil {
+		b = []byte{}
+	}
+	return b, nil
+}
+
+func (m *MsgExecuteContract) Unmarshal(b []byte) error {
+	*m = MsgExecuteContract{}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if typ != protowire.BytesType || num == 4 || num > 5 {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		switch num {
+		case 1:
+			m.Sender = string(v)
+		case 2:
+			m.Contract = string(v)
+		case 3:
+			m.Msg = bytes.Clone(v)
+		case 5:
+			var coin sdk.Coin
+			err := coin.Unmarshal(v)
+			if err != nil {
+				return err
+			}
+			m.Funds = append(m.Funds, coin)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package wasm
+
+import (
+	"encoding/hex"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// golden is the encoding of the message below by the MsgExecuteContract of wasmd v0.53.4
+const golden = "0a0c73746172733173656e646572120e737461727331636f6e74726163741a3c7b227472616e73666572223a7b22616d6f756e74223a2231303030222c22726563697069656e74223a22737461727331726563697069656e74227d7d2a0a0a057561746f6d1201352a0c0a0675737461727312023130"
+
+func TestMsgExecuteContractEncoding(t *testing.T) {
+	msg := &MsgExecuteContract{
+		Sender:   "stars1sender",
+		Contract: "stars1contract",
+		Msg:      []byte(`{"transfer":{"amount":"1000","recipient":"stars1recipient"}}`),
+		Funds:    sdk.NewCoins(sdk.NewInt64Coin("uatom", 5), sdk.NewInt64Coin("ustars", 10)),
+	}
+	bz, err := msg.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(bz) != golden {
+		t.Fatalf("encoding differs from wasmd:\n got %x\nwant %s", bz, golden)
+	}
+	if msg.Size() != len(bz) {
+		t.Fatalf("expected size %d, got %d", len(bz), msg.Size())
+	}
+
+	goldenBytes, err := hex.DecodeString(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded MsgExecuteContract
+	err = decoded.Unmarshal(goldenBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Sender != msg.Sender || decoded.Contract != msg.Contract || string(decoded.Msg) != string(msg.Msg) || !decoded.Funds.Equal(msg.Funds) {
+		t.Fatalf("unexpected decoded message %s with funds %s", decoded.String(), decoded.Funds)
+	}
+}
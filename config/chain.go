@@ -0,0 +1,66 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	env "github.com/sethvargo/go-envconfig"
+)
+
+// ChainConfig is a chain served by the faucet in addition to the default chain of FAUCET_CLIENT_*
+type ChainConfig struct {
+	// Name is the name channel profiles use to select the chain, the default chain has an empty name
+	Name string
+	// Mnemonics defaults to FAUCET_MNEMONICS
+	Mnemonics string `env:"MNEMONICS"`
+	// ExplorerURL defaults to FAUCET_EXPLORER_URL
+	ExplorerURL  string `env:"EXPLORER_URL"`
+	ClientConfig ClientConfig
+}
+
+// ChainFile is a chain as written in the config file, Env holds the FAUCET_CLIENT_* settings without
+// the prefix plus MNEMONICS and EXPLORER_URL
+type ChainFile struct {
+	Name string            `yaml:"name" toml:"name"`
+	Env  map[string]string `yaml:"env" toml:"env"`
+}
+
+// chainEnvPrefix is the prefix of the environment variables that override the settings of a chain
+func chainEnvPrefix(name string) string {
+	return "FAUCET_CHAIN_" + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name)) + "_"
+}
+
+// processChain reads the settings of a chain from the file and the environment, environment
+// variables such as FAUCET_CHAIN_OSMOSIS_CHAIN_ID override the file
+func processChain(ctx context.Context, chain ChainFile) (ChainConfig, error) {
+	cfg := ChainConfig{Name: chain.Name}
+	if chain.Name == "" {
+		return cfg, fmt.Errorf("chain name is required")
+	}
+	lookuper := env.MultiLookuper(env.PrefixLookuper(chainEnvPrefix(chain.Name), env.OsLookuper()), env.MapLookuper(chain.Env))
+	err := env.ProcessWith(ctx, &env.Config{Target: &cfg, Lookuper: lookuper})
+	if err != nil {
+		return cfg, fmt.Errorf("chain %s: %w", chain.Name, err)
+	}
+	return cfg, nil
+}
+
+// Chain returns the chain by name, the empty name is the default chain
+func (c *Config) Chain(name string) (ChainConfig, bool) {
+	if name == "" {
+		return ChainConfig{Mnemonics: c.FaucetMnemonics, ExplorerURL: c.ExplorerURL, ClientConfig: c.ClientConfig}, true
+	}
+	for _, chain := range c.Chains {
+		if chain.Name == name {
+			if chain.Mnemonics == "" {
+				chain.Mnemonics = c.FaucetMnemonics
+			}
+			if chain.ExplorerURL == "" {
+				chain.ExplorerURL = c.ExplorerURL
+			}
+			return chain, true
+		}
+	}
+	return ChainConfig{}, false
+}
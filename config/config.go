@@ -2,6 +2,9 @@ package config
 
 import (
 	"context"
+	"net/netip"
+	"os"
+	"sort"
 	"strings"
 	"time"
 
@@ -23,8 +26,66 @@ type Config struct {
 	ExplorerURL  string       `env:"FAUCET_EXPLORER_URL"`
 
 	StorePath string `env:"FAUCET_STORE_PATH, default=faucet-data"`
+	// StoreBackend is one of pebble, badger, sqlite or memory
+	StoreBackend string `env:"FAUCET_STORE_BACKEND, default=pebble"`
 
 	DisableWelcomeMessage bool `env:"DISABLE_WELCOME_MESSAGE, default=false"`
+	// LegacyCommands enables the `$request <address>` text command, it requires the message content intent
+	LegacyCommands bool `env:"FAUCET_LEGACY_COMMANDS, default=true"`
+
+	// BatchWindow is how long pending requests are collected into a single transaction, zero disables batching
+	BatchWindow time.Duration `env:"FAUCET_BATCH_WINDOW, default=0s"`
+	// BatchSize is the maximum number of requests sent in a single transaction
+	BatchSize int `env:"FAUCET_BATCH_SIZE, default=20"`
+
+	// TreasuryMnemonics is the mnemonic of the treasury account that tops up the hot wallets
+	TreasuryMnemonics string `env:"FAUCET_TREASURY_MNEMONICS"`
+	// TreasuryIndex derives the treasury from FAUCET_MNEMONICS at this index instead of a separate mnemonic
+	TreasuryIndex *uint32 `env:"FAUCET_TREASURY_INDEX, noinit"`
+	// TopUpThreshold is the balance of each denom below which a hot wallet is topped up
+	// Example: FAUCET_TOPUP_THRESHOLD="1_000_000_000ustars"
+	TopUpThreshold ChannelConfig `env:"FAUCET_TOPUP_THRESHOLD"`
+	// TopUpAmount is the amount of each denom sent to a hot wallet that is below the threshold
+	TopUpAmount ChannelConfig `env:"FAUCET_TOPUP_AMOUNT"`
+	// TopUpInterval is how often the hot wallet balances are checked
+	TopUpInterval time.Duration `env:"FAUCET_TOPUP_INTERVAL, default=1m"`
+	// TopUpCooldown is the minimum time between two top-ups of the same wallet
+	TopUpCooldown time.Duration `env:"FAUCET_TOPUP_COOLDOWN, default=10m"`
+
+	// BalanceCheckInterval is how often the wallet balances are checked to pause or resume channels
+	BalanceCheckInterval time.Duration `env:"FAUCET_BALANCE_CHECK_INTERVAL, default=1m"`
+	// AlertChannelID is the discord channel id that receives admin alerts
+	AlertChannelID string `env:"FAUCET_ALERT_CHANNEL_ID"`
+	// AlertWebhookURL is a discord or slack compatible webhook that receives admin alerts
+	AlertWebhookURL string `env:"FAUCET_ALERT_WEBHOOK_URL"`
+
+	// Port is the port the HTTP API listens on
+	Port int `env:"PORT, default=8080"`
+	// APIToken is required as a bearer token on API requests, the request endpoints are disabled without it
+	APIToken string `env:"FAUCET_API_TOKEN"`
+	// AdminToken if set enables the history endpoints of the API, it's required as a bearer token
+	AdminToken string `env:"FAUCET_ADMIN_TOKEN"`
+	// APIChannel is the channel config used by API requests that don't specify one
+	APIChannel string `env:"FAUCET_API_CHANNEL"`
+	// APITrustedProxies are the addresses or CIDR ranges of the proxies whose X-Forwarded-For header is honored
+	// Example: FAUCET_API_TRUSTED_PROXIES="10.0.0.0/8,127.0.0.1"
+	APITrustedProxies []string `env:"FAUCET_API_TRUSTED_PROXIES"`
+
+	// CooldownSweepInterval is how often expired cooldown keys are deleted from the store
+	CooldownSweepInterval time.Duration `env:"FAUCET_COOLDOWN_SWEEP_INTERVAL, default=1h"`
+
+	// VerifyChain checks on startup that the chain id matches the node, that the channel denoms exist
+	// and that the faucet accounts exist
+	VerifyChain bool `env:"FAUCET_VERIFY_CHAIN, default=false"`
+
+	// ConfigFile is the path of the optional YAML or TOML config file
+	ConfigFile string `env:"FAUCET_CONFIG_FILE"`
+
+	// Chains are the chains of the config file served in addition to the default chain
+	Chains []ChainConfig
+
+	// Channels are the channel profiles of the config file merged with the channel env maps
+	Channels []ChannelProfile
 }
 
 type ClientConfig struct {
@@ -35,6 +96,26 @@ type ClientConfig struct {
 	GasPrices     string `env:"GAS_PRICES, required"`
 	CoinType      uint32 `env:"COIN_TYPE, default=118"`
 	ChainID       string `env:"CHAIN_ID, required"`
+	// ConfirmTimeout is how long to wait for a transaction to be included in a block, zero disables confirmation
+	ConfirmTimeout time.Duration `env:"CONFIRM_TIMEOUT, default=0s"`
+	// Accounts is the number of hot wallets derived from the mnemonic at indexes 0..N-1
+	Accounts uint32 `env:"ACCOUNTS, default=1"`
+	// GasAdjustment is multiplied with the simulated gas to get the gas limit
+	GasAdjustment float64 `env:"GAS_ADJUSTMENT, default=1.5"`
+	// SimulateGas estimates the gas through simulation, GasAmount is used when simulation fails
+	SimulateGas bool `env:"SIMULATE_GAS, default=true"`
+	// FeeMode selects how the gas price is discovered: static, node, feemarket or auto
+	FeeMode string `env:"FEE_MODE, default=static"`
+	// FeeCacheTTL is how long a discovered gas price is cached
+	FeeCacheTTL time.Duration `env:"FEE_CACHE_TTL, default=1m"`
+	// MaxGasPrice caps the discovered gas price
+	// Example: FAUCET_CLIENT_MAX_GAS_PRICE="0.1ustars"
+	MaxGasPrice string `env:"MAX_GAS_PRICE"`
+	// KeyAlgo is the key algorithm of the wallets, secp256k1 or eth_secp256k1 for ethermint based chains
+	KeyAlgo string `env:"KEY_ALGO, default=secp256k1"`
+	// AuthzGranter is the cold treasury that granted the wallets a send authorization, the coins are sent
+	// on its behalf with authz so the wallets only hold the fees
+	AuthzGranter string `env:"AUTHZ_GRANTER"`
 }
 
 type ChannelConfig struct {
@@ -52,10 +133,195 @@ func (cg *ChannelConfig) UnmarshalText(text []byte) error {
 	return nil
 }
 
+// ChannelProfile holds the settings of a faucet channel
+type ChannelProfile struct {
+	// Name is the discord channel name, API requests use it to select the channel
+	Name string `json:"name"`
+	// ID is the discord channel id, it's used as the channel key when there is no name
+	ID string `json:"id"`
+	// GuildID restricts the profile to a discord server
+	GuildID string `json:"guild_id"`
+	Coins   string `json:"coins"`
+	// Interval is the cooldown between requests, zero uses the default
+	Interval time.Duration `json:"interval"`
+	// ExplorerURL overrides FAUCET_EXPLORER_URL in the channel
+	ExplorerURL string          `json:"explorer_url"`
+	Messages    ChannelMessages `json:"messages"`
+	// Chain is the name of the chain the channel sends from, empty for the default chain
+	Chain string `json:"chain"`
+	// IBC sends the coins to an address of a counterparty chain instead of the faucet chain
+	IBC *ChannelIBC `json:"ibc,omitempty"`
+	// FeeGrant grants a fee allowance limited to the coins instead of sending them
+	FeeGrant *ChannelFeeGrant `json:"fee_grant,omitempty"`
+}
+
+// ChannelIBC is the ICS-20 transfer channel of a channel profile
+type ChannelIBC struct {
+	// SourcePort is the port of the transfer channel on the faucet chain, defaults to transfer
+	SourcePort string `json:"source_port"`
+	// SourceChannel is the transfer channel on the faucet chain such as channel-0
+	SourceChannel string `json:"source_channel"`
+	// Prefix is the account prefix of the counterparty chain, recipients are validated against it
+	Prefix string `json:"prefix"`
+	// Timeout is how long the packet has to be received by the counterparty chain
+	Timeout time.Duration `json:"timeout"`
+}
+
+// ChannelFeeGrant is the x/feegrant allowance of a channel profile, the channel coins are its spend limit
+type ChannelFeeGrant struct {
+	// Expiration is how long the allowance is valid after it's granted
+	Expiration time.Duration `json:"expiration"`
+	// Period makes the allowance periodic, PeriodLimit can be spent in each period
+	Period      time.Duration `json:"period"`
+	PeriodLimit string        `json:"period_limit"`
+}
+
+// ChannelMessages customizes the replies in a channel, empty messages use the defaults
+type ChannelMessages struct {
+	// Welcome replaces the welcome message
+	Welcome string `yaml:"welcome" toml:"welcome" json:"welcome"`
+	// Success is added to the reply of successful requests
+	Success string `yaml:"success" toml:"success" json:"success"`
+	// Empty replaces the reply when the faucet is empty
+	Empty string `yaml:"empty" toml:"empty" json:"empty"`
+}
+
+// Key returns the key of the channel in FaucetChannelCoins and FaucetChannelInterval
+func (p *ChannelProfile) Key() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return p.ID
+}
+
+// TrustedProxies returns the parsed FAUCET_API_TRUSTED_PROXIES, invalid entries are reported by Validate
+func (c *Config) TrustedProxies() []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(c.APITrustedProxies))
+	for _, proxy := range c.APITrustedProxies {
+		if prefix, err := parseProxy(proxy); err == nil {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
+// parseProxy parses an address or a CIDR range, an address is a range with a single address
+func parseProxy(proxy string) (netip.Prefix, error) {
+	proxy = strings.TrimSpace(proxy)
+	if strings.Contains(proxy, "/") {
+		return netip.ParsePrefix(proxy)
+	}
+	addr, err := netip.ParseAddr(proxy)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// Channel returns the profile of the channel by key or by channel id
+func (c *Config) Channel(key string) (ChannelProfile, bool) {
+	for _, profile := range c.Channels {
+		if profile.Key() == key || profile.ID == key {
+			return profile, true
+		}
+	}
+	return ChannelProfile{}, false
+}
+
+// LookupChannel returns the profile of a discord channel, it matches by channel id first, then by
+// "guildID/name" and last by name. Profiles with an id or a guild only match that channel or guild
+// so a channel with the same name in another server doesn't get the faucet
+func (c *Config) LookupChannel(guildID, channelID, name string) (ChannelProfile, bool) {
+	inGuild := func(profile ChannelProfile) bool {
+		return profile.GuildID == "" || profile.GuildID == guildID
+	}
+	for _, profile := range c.Channels {
+		if (profile.ID == channelID || profile.Key() == channelID) && inGuild(profile) {
+			return profile, true
+		}
+	}
+	for _, profile := range c.Channels {
+		if profile.Key() == guildID+"/"+name {
+			return profile, true
+		}
+	}
+	for _, profile := range c.Channels {
+		if profile.ID == "" && profile.Name == name && inGuild(profile) {
+			return profile, true
+		}
+	}
+	return ChannelProfile{}, false
+}
+
+// NewConfig reads the config file set by FAUCET_CONFIG_FILE if any and the environment, environment
+// variables override the settings of the file
 func NewConfig() (*Config, error) {
+	lookuper := env.OsLookuper()
+	var (
+		profiles []ChannelProfile
+		chains   []ChainFile
+	)
+	if path := os.Getenv("FAUCET_CONFIG_FILE"); path != "" {
+		file, err := ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		profiles, err = file.Profiles()
+		if err != nil {
+			return nil, err
+		}
+		chains = file.Chains
+		lookuper = env.MultiLookuper(lookuper, env.MapLookuper(file.Env))
+	}
 	var cfg Config
-	if err := env.Process(context.Background(), &cfg); err != nil {
+	err := env.ProcessWith(context.Background(), &env.Config{Target: &cfg, Lookuper: lookuper})
+	if err != nil {
 		return nil, err
 	}
+	for _, chain := range chains {
+		chainConfig, err := processChain(context.Background(), chain)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Chains = append(cfg.Chains, chainConfig)
+	}
+	cfg.mergeChannels(profiles)
 	return &cfg, nil
 }
+
+// mergeChannels merges the channel profiles with the channel env maps, the env maps take precedence
+func (c *Config) mergeChannels(profiles []ChannelProfile) {
+	if c.FaucetChannelCoins == nil {
+		c.FaucetChannelCoins = make(map[string]ChannelConfig)
+	}
+	if c.FaucetChannelInterval == nil {
+		c.FaucetChannelInterval = make(map[string]time.Duration)
+	}
+	seen := make(map[string]bool)
+	for _, profile := range profiles {
+		key := profile.Key()
+		seen[key] = true
+		if coins, ok := c.FaucetChannelCoins[key]; ok {
+			profile.Coins = coins.Coins
+		}
+		if interval, ok := c.FaucetChannelInterval[key]; ok {
+			profile.Interval = interval
+		}
+		c.FaucetChannelCoins[key] = ChannelConfig{Coins: profile.Coins}
+		if profile.Interval > 0 {
+			c.FaucetChannelInterval[key] = profile.Interval
+		}
+		c.Channels = append(c.Channels, profile)
+	}
+	keys := make([]string, 0, len(c.FaucetChannelCoins))
+	for key := range c.FaucetChannelCoins {
+		if !seen[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		c.Channels = append(c.Channels, ChannelProfile{Name: key, Coins: c.FaucetChannelCoins[key].Coins, Interval: c.FaucetChannelInterval[key]})
+	}
+}
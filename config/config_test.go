@@ -3,17 +3,25 @@ package config_test
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/public-awesome/faucet/client"
 	"github.com/public-awesome/faucet/config"
 	env "github.com/sethvargo/go-envconfig"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestConfig(t *testing.T) {
-	os.Setenv("FAUCET_CHANNEL_AMOUNTS", "faucet:10_000_000ustars;private-faucet:10_000_000ustars,1factory/stars123456789;🚰│faucet:1ustars,1uatom,1uinit;1234567891012345:1ustars")
-	os.Setenv("FAUCET_CHANNEL_INTERVAL", "faucet:1h;private-faucet:190h")
+	t.Setenv("FAUCET_CHANNEL_AMOUNTS", "faucet:10_000_000ustars;private-faucet:10_000_000ustars,1factory/stars123456789;🚰│faucet:1ustars,1uatom,1uinit;1234567891012345:1ustars")
+	t.Setenv("FAUCET_CHANNEL_INTERVAL", "faucet:1h;private-faucet:190h")
+	t.Setenv("FAUCET_BOT_TOKEN", "token")
+	t.Setenv("FAUCET_CLIENT_RPC_ENDPOINT", "http://localhost:26657")
+	t.Setenv("FAUCET_CLIENT_API_ENDPOINT", "http://localhost:1317")
+	t.Setenv("FAUCET_CLIENT_ACCOUNT_PREFIX", "stars")
+	t.Setenv("FAUCET_CLIENT_GAS_PRICES", "1ustars")
+	t.Setenv("FAUCET_CLIENT_CHAIN_ID", "elgafar-1")
 	cfg := &config.Config{}
 	err := env.Process(context.Background(), cfg)
 	assert.NoError(t, err)
@@ -29,4 +37,191 @@ func TestConfig(t *testing.T) {
 		"faucet":         1 * time.Hour,
 		"private-faucet": 190 * time.Hour,
 	})
+	assert.True(t, cfg.LegacyCommands, "the $request command must stay enabled by default")
+}
+
+func TestConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "faucet.yaml")
+	err := os.WriteFile(path, []byte(`
+env:
+  FAUCET_BOT_TOKEN: file-token
+  FAUCET_CLIENT_CHAIN_ID: file-chain
+channels:
+  - name: faucet
+    coins: 1_000ustars
+    interval: 2h
+    explorer_url: https://explorer.example/tx
+    messages:
+      success: welcome aboard
+  - id: "1234567891012345"
+    guild: "42"
+    coins: 5ustars
+    ibc:
+      source_channel: channel-0
+      prefix: osmo
+`), 0o600)
+	assert.NoError(t, err)
+	t.Setenv("FAUCET_CONFIG_FILE", path)
+	t.Setenv("FAUCET_BOT_TOKEN", "env-token")
+	t.Setenv("FAUCET_CLIENT_CHAIN_ID", "")
+	assert.NoError(t, os.Unsetenv("FAUCET_CLIENT_CHAIN_ID"))
+	t.Setenv("FAUCET_CHANNEL_AMOUNTS", "faucet:7ustars;other:1ustars")
+	t.Setenv("FAUCET_CHANNEL_INTERVAL", "other:1h")
+	t.Setenv("FAUCET_CLIENT_RPC_ENDPOINT", "http://localhost:26657")
+	t.Setenv("FAUCET_CLIENT_API_ENDPOINT", "http://localhost:1317")
+	t.Setenv("FAUCET_CLIENT_ACCOUNT_PREFIX", "stars")
+	t.Setenv("FAUCET_CLIENT_GAS_PRICES", "1ustars")
+
+	cfg, err := config.NewConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "env-token", cfg.FaucetBotToken)
+	assert.Equal(t, "file-chain", cfg.ClientConfig.ChainID)
+	assert.Equal(t, map[string]config.ChannelConfig{
+		"faucet":           {Coins: "7ustars"},
+		"1234567891012345": {Coins: "5ustars"},
+		"other":            {Coins: "1ustars"},
+	}, cfg.FaucetChannelCoins)
+
+	profile, ok := cfg.Channel("faucet")
+	assert.True(t, ok)
+	assert.Equal(t, config.ChannelProfile{
+		Name:        "faucet",
+		Coins:       "7ustars",
+		Interval:    2 * time.Hour,
+		ExplorerURL: "https://explorer.example/tx",
+		Messages:    config.ChannelMessages{Success: "welcome aboard"},
+	}, profile)
+	profile, ok = cfg.Channel("1234567891012345")
+	assert.True(t, ok)
+	assert.Equal(t, "42", profile.GuildID)
+	assert.Equal(t, &config.ChannelIBC{SourcePort: "transfer", SourceChannel: "channel-0", Prefix: "osmo", Timeout: 10 * time.Minute}, profile.IBC)
+	profile, ok = cfg.Channel("other")
+	assert.True(t, ok)
+	assert.Equal(t, time.Hour, profile.Interval)
+}
+
+func TestValidate(t *testing.T) {
+	cfg := &config.Config{
+		FaucetChannelCoins:    map[string]config.ChannelConfig{"faucet": {Coins: "10000ustars"}, "typo": {Coins: "10000"}},
+		FaucetChannelInterval: map[string]time.Duration{"missing": time.Hour},
+		Channels: []config.ChannelProfile{
+			{Name: "faucet", Coins: "10000ustars"},
+			{Name: "typo", Coins: "10000", Interval: -time.Hour},
+		},
+		BatchSize: 20,
+		ClientConfig: config.ClientConfig{
+			GasPrices: "0.1ustars",
+			FeeMode:   "static",
+			Accounts:  1,
+			KeyAlgo:   "secp256k1",
+		},
+	}
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "channel typo: invalid coins")
+	assert.ErrorContains(t, err, "channel typo: negative interval")
+	assert.ErrorContains(t, err, "FAUCET_CHANNEL_INTERVAL: channel missing has no amount")
+
+	cfg.Channels = cfg.Channels[:1]
+	cfg.FaucetChannelInterval = nil
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, []string{"ustars"}, cfg.Denoms(""))
+
+	cfg.Channels[0].FeeGrant = &config.ChannelFeeGrant{Expiration: time.Hour, Period: time.Hour, PeriodLimit: "20000ustars"}
+	assert.ErrorContains(t, cfg.Validate(), "channel faucet: fee grant period limit 20000ustars exceeds the coins")
+	cfg.Channels[0].FeeGrant.PeriodLimit = "1000ustars"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.ClientConfig.MaxGasPrice = "1uatom"
+	assert.ErrorContains(t, cfg.Validate(), "FAUCET_CLIENT_MAX_GAS_PRICE: gas price 1.000000000000000000uatom must be in the gas price denom ustars")
+	cfg.ClientConfig.MaxGasPrice = ""
+
+	cfg.ClientConfig.GasPrices = "0.1ustars,0.2uatom"
+	assert.ErrorContains(t, cfg.Validate(), `FAUCET_CLIENT_GAS_PRICES: invalid gas price "0.1ustars,0.2uatom", a single coin is required`)
+	cfg.ClientConfig.GasPrices = "0.1ustars"
+
+	cfg.ClientConfig.FeeMode, cfg.ClientConfig.KeyAlgo = "dynamic", "ed25519"
+	err = cfg.Validate()
+	assert.ErrorContains(t, err, `FAUCET_CLIENT_FEE_MODE: unknown fee mode "dynamic"`)
+	assert.ErrorContains(t, err, `FAUCET_CLIENT_KEY_ALGO: unknown key algorithm "ed25519"`)
+	cfg.ClientConfig.FeeMode, cfg.ClientConfig.KeyAlgo = client.FeeModeAuto, client.KeyAlgoEthSecp256k1
+	assert.NoError(t, cfg.Validate())
+
+	cfg.ClientConfig.AccountPrefix = "stars"
+	cfg.ClientConfig.AuthzGranter = "stars1qypqxpq9qcrsszg2pvxq6rs0zqg3yyc5t7mrdd"
+	assert.ErrorContains(t, cfg.Validate(), "channel faucet: fee grants are not supported in authz mode")
+	cfg.Channels[0].FeeGrant = nil
+	cfg.Channels[0].IBC = &config.ChannelIBC{SourcePort: "transfer", SourceChannel: "channel-0", Prefix: "osmo", Timeout: time.Minute}
+	err = cfg.Validate()
+	assert.ErrorContains(t, err, "channel faucet: ibc transfers are not supported in authz mode")
+	assert.NotContains(t, err.Error(), "AUTHZ_GRANTER")
+}
+
+func TestLookupChannel(t *testing.T) {
+	cfg := &config.Config{
+		Channels: []config.ChannelProfile{
+			{Name: "faucet", Coins: "1ustars"},
+			{Name: "1234567891012345", Coins: "2ustars"},
+			{Name: "42/faucet", Coins: "3ustars"},
+			{Name: "renamed", ID: "555", Coins: "4ustars"},
+			{Name: "scoped", GuildID: "7", Coins: "5ustars"},
+		},
+	}
+	lookup := func(guildID, channelID, name string) string {
+		profile, ok := cfg.LookupChannel(guildID, channelID, name)
+		if !ok {
+			return ""
+		}
+		return profile.Coins
+	}
+	assert.Equal(t, "1ustars", lookup("1", "100", "faucet"))
+	assert.Equal(t, "2ustars", lookup("1", "1234567891012345", "faucet"))
+	assert.Equal(t, "3ustars", lookup("42", "100", "faucet"))
+	assert.Equal(t, "4ustars", lookup("1", "555", "anything"))
+	assert.Equal(t, "", lookup("1", "100", "renamed"))
+	assert.Equal(t, "5ustars", lookup("7", "100", "scoped"))
+	assert.Equal(t, "", lookup("8", "100", "scoped"))
+}
+
+func TestConfigChains(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "faucet.yaml")
+	err := os.WriteFile(path, []byte(`
+chains:
+  - name: osmosis
+    env:
+      CHAIN_ID: osmo-test-5
+      ACCOUNT_PREFIX: osmo
+      GAS_PRICES: 0.025uosmo
+      RPC_ENDPOINT: http://osmosis:26657
+      API_ENDPOINT: http://osmosis:1317
+channels:
+  - name: osmo-faucet
+    chain: osmosis
+    coins: 1_000uosmo
+`), 0o600)
+	assert.NoError(t, err)
+	t.Setenv("FAUCET_CONFIG_FILE", path)
+	t.Setenv("FAUCET_BOT_TOKEN", "token")
+	t.Setenv("FAUCET_MNEMONICS", "default mnemonic")
+	t.Setenv("FAUCET_CHAIN_OSMOSIS_CHAIN_ID", "osmo-test-6")
+	t.Setenv("FAUCET_CHANNEL_AMOUNTS", "faucet:7ustars")
+	t.Setenv("FAUCET_CHANNEL_INTERVAL", "faucet:1h")
+	t.Setenv("FAUCET_CLIENT_CHAIN_ID", "elgafar-1")
+	t.Setenv("FAUCET_CLIENT_RPC_ENDPOINT", "http://localhost:26657")
+	t.Setenv("FAUCET_CLIENT_API_ENDPOINT", "http://localhost:1317")
+	t.Setenv("FAUCET_CLIENT_ACCOUNT_PREFIX", "stars")
+	t.Setenv("FAUCET_CLIENT_GAS_PRICES", "1ustars")
+
+	cfg, err := config.NewConfig()
+	assert.NoError(t, err)
+	assert.NoError(t, cfg.Validate())
+	chain, ok := cfg.Chain("osmosis")
+	assert.True(t, ok)
+	assert.Equal(t, "osmo-test-6", chain.ClientConfig.ChainID)
+	assert.Equal(t, "osmo", chain.ClientConfig.AccountPrefix)
+	assert.Equal(t, "default mnemonic", chain.Mnemonics)
+	assert.Equal(t, []string{"uosmo"}, cfg.Denoms("osmosis"))
+	assert.Equal(t, []string{"ustars"}, cfg.Denoms(""))
+
+	cfg.Channels = append(cfg.Channels, config.ChannelProfile{Name: "typo", Chain: "osmo", Coins: "1uosmo"})
+	assert.ErrorContains(t, cfg.Validate(), `channel typo: unknown chain "osmo"`)
 }
@@ -0,0 +1,162 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// File is the optional config file, Env holds settings by environment variable name so every
+// setting can be set in the file and overridden by the environment
+type File struct {
+	Env      map[string]string `yaml:"env" toml:"env"`
+	Chains   []ChainFile       `yaml:"chains" toml:"chains"`
+	Channels []ChannelFile     `yaml:"channels" toml:"channels"`
+}
+
+// ChannelFile is a channel profile as written in the config file
+type ChannelFile struct {
+	Name        string          `yaml:"name" toml:"name"`
+	ID          string          `yaml:"id" toml:"id"`
+	Guild       string          `yaml:"guild" toml:"guild"`
+	Coins       string          `yaml:"coins" toml:"coins"`
+	Interval    string          `yaml:"interval" toml:"interval"`
+	ExplorerURL string          `yaml:"explorer_url" toml:"explorer_url"`
+	Messages    ChannelMessages `yaml:"messages" toml:"messages"`
+	Chain       string          `yaml:"chain" toml:"chain"`
+	IBC         *ChannelIBCFile `yaml:"ibc" toml:"ibc"`
+	// FeeGrant makes the channel grant fee allowances instead of sending coins
+	FeeGrant *ChannelFeeGrantFile `yaml:"fee_grant" toml:"fee_grant"`
+}
+
+// ChannelIBCFile is the IBC transfer channel of a profile as written in the config file
+type ChannelIBCFile struct {
+	SourcePort    string `yaml:"source_port" toml:"source_port"`
+	SourceChannel string `yaml:"source_channel" toml:"source_channel"`
+	Prefix        string `yaml:"prefix" toml:"prefix"`
+	Timeout       string `yaml:"timeout" toml:"timeout"`
+}
+
+// ChannelFeeGrantFile is the fee allowance of a profile as written in the config file
+type ChannelFeeGrantFile struct {
+	Expiration  string `yaml:"expiration" toml:"expiration"`
+	Period      string `yaml:"period" toml:"period"`
+	PeriodLimit string `yaml:"period_limit" toml:"period_limit"`
+}
+
+const (
+	// defaultIBCTimeout is the packet timeout of IBC channels that don't set one
+	defaultIBCTimeout = 10 * time.Minute
+	// defaultFeeGrantExpiration is the expiration of fee allowances of channels that don't set one
+	defaultFeeGrantExpiration = 7 * 24 * time.Hour
+)
+
+// ReadFile reads a YAML or TOML config file, the format is chosen by the extension
+func ReadFile(path string) (*File, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file File
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &file)
+	case ".toml":
+		err = toml.Unmarshal(b, &file)
+	default:
+		return nil, fmt.Errorf("unsupported config file format %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	return &file, nil
+}
+
+// Profiles returns the channel profiles of the file
+func (f *File) Profiles() ([]ChannelProfile, error) {
+	profiles := make([]ChannelProfile, 0, len(f.Channels))
+	for i, channel := range f.Channels {
+		if channel.Name == "" && channel.ID == "" {
+			return nil, fmt.Errorf("channel %d: name or id is required", i)
+		}
+		var coins ChannelConfig
+		err := coins.UnmarshalText([]byte(channel.Coins))
+		if err != nil {
+			return nil, fmt.Errorf("channel %d: %w", i, err)
+		}
+		var interval time.Duration
+		if channel.Interval != "" {
+			interval, err = time.ParseDuration(channel.Interval)
+			if err != nil {
+				return nil, fmt.Errorf("channel %d: invalid interval: %w", i, err)
+			}
+		}
+		var ibc *ChannelIBC
+		if channel.IBC != nil {
+			ibc = &ChannelIBC{
+				SourcePort:    channel.IBC.SourcePort,
+				SourceChannel: channel.IBC.SourceChannel,
+				Prefix:        channel.IBC.Prefix,
+				Timeout:       defaultIBCTimeout,
+			}
+			if ibc.SourcePort == "" {
+				ibc.SourcePort = "transfer"
+			}
+			if channel.IBC.Timeout != "" {
+				ibc.Timeout, err = time.ParseDuration(channel.IBC.Timeout)
+				if err != nil {
+					return nil, fmt.Errorf("channel %d: invalid ibc timeout: %w", i, err)
+				}
+			}
+		}
+		var feeGrant *ChannelFeeGrant
+		if channel.FeeGrant != nil {
+			feeGrant, err = channel.FeeGrant.profile()
+			if err != nil {
+				return nil, fmt.Errorf("channel %d: %w", i, err)
+			}
+		}
+		profiles = append(profiles, ChannelProfile{
+			Name:        channel.Name,
+			ID:          channel.ID,
+			GuildID:     channel.Guild,
+			Coins:       coins.Coins,
+			Interval:    interval,
+			ExplorerURL: channel.ExplorerURL,
+			Messages:    channel.Messages,
+			Chain:       channel.Chain,
+			IBC:         ibc,
+			FeeGrant:    feeGrant,
+		})
+	}
+	return profiles, nil
+}
+
+func (f *ChannelFeeGrantFile) profile() (*ChannelFeeGrant, error) {
+	feeGrant := &ChannelFeeGrant{Expiration: defaultFeeGrantExpiration}
+	var err error
+	if f.Expiration != "" {
+		feeGrant.Expiration, err = time.ParseDuration(f.Expiration)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fee grant expiration: %w", err)
+		}
+	}
+	if f.Period != "" {
+		feeGrant.Period, err = time.ParseDuration(f.Period)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fee grant period: %w", err)
+		}
+	}
+	var limit ChannelConfig
+	err = limit.UnmarshalText([]byte(f.PeriodLimit))
+	if err != nil {
+		return nil, err
+	}
+	feeGrant.PeriodLimit = limit.Coins
+	return feeGrant, nil
+}
@@ -0,0 +1,208 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/public-awesome/faucet/client"
+)
+
+// Validate checks the channel amounts and intervals and the fee settings, every invalid setting is reported
+func (c *Config) Validate() error {
+	var errs []error
+	for _, profile := range c.Channels {
+		coins, err := client.ParseCoins(profile.Coins)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("channel %s: invalid coins %q: %w", profile.Key(), profile.Coins, err))
+		} else if coins.Empty() {
+			errs = append(errs, fmt.Errorf("channel %s: coins are required", profile.Key()))
+		}
+		if profile.Interval < 0 {
+			errs = append(errs, fmt.Errorf("channel %s: negative interval %s", profile.Key(), profile.Interval))
+		}
+		if profile.IBC != nil {
+			errs = append(errs, validateIBC(profile, coins)...)
+		}
+		if profile.FeeGrant != nil {
+			errs = append(errs, validateFeeGrant(profile, coins)...)
+		}
+	}
+	intervals := make([]string, 0, len(c.FaucetChannelInterval))
+	for channel := range c.FaucetChannelInterval {
+		intervals = append(intervals, channel)
+	}
+	sort.Strings(intervals)
+	for _, channel := range intervals {
+		if _, ok := c.FaucetChannelCoins[channel]; !ok {
+			errs = append(errs, fmt.Errorf("FAUCET_CHANNEL_INTERVAL: channel %s has no amount", channel))
+		}
+	}
+	for _, proxy := range c.APITrustedProxies {
+		_, err := parseProxy(proxy)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("FAUCET_API_TRUSTED_PROXIES: invalid address or range %q: %w", proxy, err))
+		}
+	}
+	if c.APIChannel != "" {
+		if _, ok := c.FaucetChannelCoins[c.APIChannel]; !ok {
+			errs = append(errs, fmt.Errorf("FAUCET_API_CHANNEL: channel %s has no amount", c.APIChannel))
+		}
+	}
+
+	errs = append(errs, validateClient("FAUCET_CLIENT_", c.ClientConfig)...)
+	chains := make(map[string]bool)
+	for _, chain := range c.Chains {
+		if chains[chain.Name] {
+			errs = append(errs, fmt.Errorf("chain %s: duplicate chain name", chain.Name))
+		}
+		chains[chain.Name] = true
+		errs = append(errs, validateClient(chainEnvPrefix(chain.Name), chain.ClientConfig)...)
+	}
+	for _, profile := range c.Channels {
+		chain, ok := c.Chain(profile.Chain)
+		if !ok {
+			errs = append(errs, fmt.Errorf("channel %s: unknown chain %q", profile.Key(), profile.Chain))
+			continue
+		}
+		// only bank sends are executed on behalf of the granter, transfers and allowances would be paid by the hot wallets
+		if chain.ClientConfig.AuthzGranter != "" && profile.IBC != nil {
+			errs = append(errs, fmt.Errorf("channel %s: ibc transfers are not supported in authz mode", profile.Key()))
+		}
+		if chain.ClientConfig.AuthzGranter != "" && profile.FeeGrant != nil {
+			errs = append(errs, fmt.Errorf("channel %s: fee grants are not supported in authz mode", profile.Key()))
+		}
+	}
+	if c.BatchSize <= 0 {
+		errs = append(errs, errors.New("FAUCET_BATCH_SIZE: must be positive"))
+	}
+
+	if c.TopUpThreshold.Coins != "" {
+		_, err := sdk.ParseCoinsNormalized(c.TopUpThreshold.Coins)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("FAUCET_TOPUP_THRESHOLD: invalid coins %q: %w", c.TopUpThreshold.Coins, err))
+		}
+	}
+	if c.TopUpAmount.Coins != "" {
+		_, err := sdk.ParseCoinsNormalized(c.TopUpAmount.Coins)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("FAUCET_TOPUP_AMOUNT: invalid coins %q: %w", c.TopUpAmount.Coins, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateIBC checks the transfer channel of a profile, only native coins can be sent over IBC
+func validateIBC(profile ChannelProfile, coins sdk.Coins) []error {
+	var errs []error
+	if profile.IBC.SourceChannel == "" {
+		errs = append(errs, fmt.Errorf("channel %s: ibc source channel is required", profile.Key()))
+	}
+	if profile.IBC.Prefix == "" {
+		errs = append(errs, fmt.Errorf("channel %s: ibc prefix is required", profile.Key()))
+	}
+	if profile.IBC.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("channel %s: ibc timeout must be positive", profile.Key()))
+	}
+	for _, coin := range coins {
+		if _, ok := client.CW20Contract(coin.Denom); ok {
+			errs = append(errs, fmt.Errorf("channel %s: cw20 tokens can't be sent over ibc", profile.Key()))
+		}
+	}
+	return errs
+}
+
+// validateFeeGrant checks the allowance of a profile, the channel coins are its spend limit so they must
+// be native coins and the period limit can't exceed them
+func validateFeeGrant(profile ChannelProfile, coins sdk.Coins) []error {
+	var errs []error
+	if profile.IBC != nil {
+		errs = append(errs, fmt.Errorf("channel %s: fee grants can't be sent over ibc", profile.Key()))
+	}
+	if profile.FeeGrant.Expiration <= 0 {
+		errs = append(errs, fmt.Errorf("channel %s: fee grant expiration must be positive", profile.Key()))
+	}
+	for _, coin := range coins {
+		if _, ok := client.CW20Contract(coin.Denom); ok {
+			errs = append(errs, fmt.Errorf("channel %s: cw20 tokens can't be used as a fee grant limit", profile.Key()))
+		}
+	}
+	switch {
+	case profile.FeeGrant.Period < 0:
+		errs = append(errs, fmt.Errorf("channel %s: negative fee grant period %s", profile.Key(), profile.FeeGrant.Period))
+	case profile.FeeGrant.Period > 0 && profile.FeeGrant.PeriodLimit == "":
+		errs = append(errs, fmt.Errorf("channel %s: fee grant period limit is required with a period", profile.Key()))
+	case profile.FeeGrant.Period == 0 && profile.FeeGrant.PeriodLimit != "":
+		errs = append(errs, fmt.Errorf("channel %s: fee grant period limit requires a period", profile.Key()))
+	case profile.FeeGrant.PeriodLimit != "":
+		limit, err := sdk.ParseCoinsNormalized(profile.FeeGrant.PeriodLimit)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("channel %s: invalid fee grant period limit %q: %w", profile.Key(), profile.FeeGrant.PeriodLimit, err))
+		} else if !limit.IsAllLTE(coins) {
+			errs = append(errs, fmt.Errorf("channel %s: fee grant period limit %s exceeds the coins", profile.Key(), limit))
+		}
+	}
+	return errs
+}
+
+// validateClient checks the fee settings of a chain, prefix is the prefix of its environment variables
+func validateClient(prefix string, cfg ClientConfig) []error {
+	var errs []error
+	// the client pays fees in a single denom, so only one gas price is accepted
+	var gasPrice *sdk.DecCoin
+	if cfg.GasPrices == "" {
+		errs = append(errs, fmt.Errorf("%sGAS_PRICES: gas prices are required", prefix))
+	} else if price, err := sdk.ParseDecCoin(cfg.GasPrices); err != nil {
+		errs = append(errs, fmt.Errorf("%sGAS_PRICES: invalid gas price %q, a single coin is required: %w", prefix, cfg.GasPrices, err))
+	} else {
+		gasPrice = &price
+	}
+	if cfg.MaxGasPrice != "" {
+		maxPrice, err := sdk.ParseDecCoin(cfg.MaxGasPrice)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%sMAX_GAS_PRICE: invalid gas price %q: %w", prefix, cfg.MaxGasPrice, err))
+		} else if gasPrice != nil && maxPrice.Denom != gasPrice.Denom {
+			errs = append(errs, fmt.Errorf("%sMAX_GAS_PRICE: gas price %s must be in the gas price denom %s", prefix, maxPrice, gasPrice.Denom))
+		}
+	}
+	if !client.ValidFeeMode(cfg.FeeMode) {
+		errs = append(errs, fmt.Errorf("%sFEE_MODE: unknown fee mode %q", prefix, cfg.FeeMode))
+	}
+	if !client.ValidKeyAlgo(cfg.KeyAlgo) {
+		errs = append(errs, fmt.Errorf("%sKEY_ALGO: unknown key algorithm %q", prefix, cfg.KeyAlgo))
+	}
+	if cfg.Accounts == 0 {
+		errs = append(errs, fmt.Errorf("%sACCOUNTS: at least one account is required", prefix))
+	}
+	if cfg.AuthzGranter != "" {
+		_, err := sdk.GetFromBech32(cfg.AuthzGranter, cfg.AccountPrefix)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%sAUTHZ_GRANTER: invalid address %q: %w", prefix, cfg.AuthzGranter, err))
+		}
+	}
+	return errs
+}
+
+// Denoms returns the denoms handed out by the channels of the chain, CW20 tokens use a cw20:<contract> denom
+func (c *Config) Denoms(chain string) []string {
+	seen := make(map[string]bool)
+	var denoms []string
+	for _, profile := range c.Channels {
+		if profile.Chain != chain {
+			continue
+		}
+		coins, err := client.ParseCoins(profile.Coins)
+		if err != nil {
+			continue
+		}
+		for _, coin := range coins {
+			if !seen[coin.Denom] {
+				seen[coin.Denom] = true
+				denoms = append(denoms, coin.Denom)
+			}
+		}
+	}
+	sort.Strings(denoms)
+	return denoms
+}
@@ -0,0 +1,419 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/public-awesome/faucet/client"
+)
+
+// apiWaitTimeout is how long a POST waits for the transaction before returning the pending request
+const apiWaitTimeout = 30 * time.Second
+
+type APIRequest struct {
+	Address string `json:"address"`
+	// Channel is the channel name or id whose configuration is used for the request
+	Channel string `json:"channel,omitempty"`
+}
+
+type APIError struct {
+	Error      string `json:"error"`
+	RetryAfter int64  `json:"retry_after,omitempty"`
+}
+
+type APIPendingResponse struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+const responsePrefix = "response-"
+
+// responseTTL is how long the response of a request can be looked up with the API
+const responseTTL = 7 * 24 * time.Hour
+
+// responseEntry is a stored response, it's deleted by the sweeper once it expires
+type responseEntry struct {
+	*SendResponse
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func responseKey(id string) string {
+	return fmt.Sprintf("%s%s", responsePrefix, id)
+}
+
+// saveResponse stores the response and deletes the queue entry of the request in the same batch, the
+// response is the record that the request was processed
+func (s *Server) saveResponse(response *SendResponse) {
+	err := s.storeResponse(response)
+	if err != nil {
+		s.log.Error("error saving response", "error", err, "response_id", response.ID)
+	}
+
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	if ch, ok := s.pending[response.ID]; ok {
+		ch <- response
+		delete(s.pending, response.ID)
+	}
+}
+
+func (s *Server) storeResponse(response *SendResponse) error {
+	b, err := json.Marshal(responseEntry{SendResponse: response, ExpiresAt: time.Now().Add(responseTTL)})
+	if err != nil {
+		return err
+	}
+	batch := s.store.Batch()
+	defer batch.Close()
+	err = setWithTTL(batch, []byte(responseKey(response.ID)), b, responseTTL)
+	if err != nil {
+		return err
+	}
+	err = batch.Delete([]byte(queueKey(response.ID)))
+	if err != nil {
+		return err
+	}
+	return batch.Commit()
+}
+
+func (s *Server) getResponse(id string) (*SendResponse, error) {
+	b, err := s.store.Get([]byte(responseKey(id)))
+	if err != nil {
+		return nil, err
+	}
+	entry := responseEntry{SendResponse: &SendResponse{}}
+	err = json.Unmarshal(b, &entry)
+	if err != nil {
+		return nil, err
+	}
+	return entry.SendResponse, nil
+}
+
+// sweepResponses deletes the expired responses and returns how many were deleted, responses written
+// before they had an expiry are deleted as well
+func (s *Server) sweepResponses() (int, error) {
+	now := time.Now()
+	batch := s.store.Batch()
+	defer batch.Close()
+	reclaimed := 0
+	err := s.store.Iterate([]byte(responsePrefix), func(key, value []byte) error {
+		var entry responseEntry
+		err := json.Unmarshal(value, &entry)
+		if err != nil {
+			s.log.Error("error decoding response entry", "error", err, "key", string(key))
+			return nil
+		}
+		if entry.ExpiresAt.After(now) {
+			return nil
+		}
+		reclaimed++
+		return batch.Delete(key)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return reclaimed, batch.Commit()
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// remoteIP returns the address of the client, X-Forwarded-For is only honored when the request comes from
+// a trusted proxy. The header is read from the right and the first address that isn't a trusted proxy is
+// the client since anything to its left is set by the client
+func (s *Server) remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	proxies := s.config().TrustedProxies()
+	if !trusted(proxies, host) {
+		return host
+	}
+	forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(forwarded[i])
+		if ip == "" {
+			continue
+		}
+		if !trusted(proxies, ip) {
+			return ip
+		}
+		host = ip
+	}
+	return host
+}
+
+func trusted(proxies []netip.Prefix, ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, proxy := range proxies {
+		if proxy.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// authorized checks the bearer token of the request in constant time, requests are refused when no
+// token is configured
+func authorized(r *http.Request, token string) bool {
+	if token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) == 1
+}
+
+func (s *Server) handleCreateRequest(w http.ResponseWriter, r *http.Request) {
+	if !authorized(r, s.config().APIToken) {
+		writeJSON(w, http.StatusUnauthorized, APIError{Error: "unauthorized"})
+		return
+	}
+	var body APIRequest
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, APIError{Error: "invalid request body"})
+		return
+	}
+	channel := body.Channel
+	if channel == "" {
+		channel = s.config().APIChannel
+	}
+	profile, ok := s.config().Channel(channel)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, APIError{Error: fmt.Sprintf("channel %q is not configured", channel)})
+		return
+	}
+	channel = profile.Key()
+	c, err := s.chainClient(profile.Chain)
+	if err != nil {
+		s.log.Error("error getting chain client", "error", err, "channel", channel)
+		writeJSON(w, http.StatusInternalServerError, APIError{Error: "internal error"})
+		return
+	}
+	address, err := s.recipientAddress(c, profile, strings.TrimSpace(body.Address))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, APIError{Error: "invalid address"})
+		return
+	}
+	if s.isPaused(channel) {
+		writeJSON(w, http.StatusServiceUnavailable, APIError{Error: "faucet is empty, admins have been notified"})
+		return
+	}
+	requestID, err := uuid.NewV7()
+	if err != nil {
+		s.log.Error("error generating uuid", "error", err)
+		writeJSON(w, http.StatusInternalServerError, APIError{Error: "internal error"})
+		return
+	}
+	user := s.remoteIP(r)
+	block, waitTime := s.block(requestID.String(), fmt.Sprintf("%s-%s", SourceAPI, channel), address, user, s.channelInterval(channel))
+	if block {
+		w.Header().Set("Retry-After", fmt.Sprintf("%d", int64(waitTime.Seconds())))
+		writeJSON(w, http.StatusTooManyRequests, APIError{Error: "cooldown period has not elapsed", RetryAfter: time.Now().Add(waitTime).UTC().Unix()})
+		return
+	}
+	req := &SendRequest{
+		ID:          requestID.String(),
+		Source:      SourceAPI,
+		ChannelName: channel,
+		Channel:     channel,
+		Chain:       profile.Chain,
+		IBC:         profile.IBC,
+		FeeGrant:    profile.FeeGrant,
+		User:        user,
+		UserID:      user,
+		Amount:      profile.Coins,
+		Address:     address,
+	}
+	s.log.Info("sending api request", "request_id", req.ID, "channel", req.ChannelName, "user", req.User, "amount", req.Amount, "address", req.Address)
+
+	done := make(chan *SendResponse, 1)
+	s.pendingMu.Lock()
+	s.pending[req.ID] = done
+	s.pendingMu.Unlock()
+
+	err = s.enqueue(req)
+	if err != nil {
+		s.log.Error("error queueing request", "error", err, "request_id", req.ID)
+		s.releaseCooldown(req.ID)
+		s.pendingMu.Lock()
+		delete(s.pending, req.ID)
+		s.pendingMu.Unlock()
+		writeJSON(w, http.StatusInternalServerError, APIError{Error: "internal error"})
+		return
+	}
+
+	timer := time.NewTimer(apiWaitTimeout)
+	defer timer.Stop()
+	select {
+	case response := <-done:
+		status := http.StatusOK
+		switch {
+		case response.Pending:
+			status = http.StatusAccepted
+		case !response.Success:
+			status = http.StatusBadGateway
+		}
+		writeJSON(w, status, response)
+	case <-timer.C:
+		status := StatusQueued
+		if entry, err := s.getQueueEntry(req.ID); err == nil {
+			status = entry.Status
+		}
+		writeJSON(w, http.StatusAccepted, APIPendingResponse{ID: req.ID, Status: status})
+	case <-r.Context().Done():
+	}
+}
+
+func (s *Server) handleGetRequest(w http.ResponseWriter, r *http.Request) {
+	if !authorized(r, s.config().APIToken) {
+		writeJSON(w, http.StatusUnauthorized, APIError{Error: "unauthorized"})
+		return
+	}
+	id := r.PathValue("id")
+	response, err := s.getResponse(id)
+	if errors.Is(err, ErrNotFound) {
+		if entry, err := s.getQueueEntry(id); err == nil {
+			writeJSON(w, http.StatusAccepted, APIPendingResponse{ID: id, Status: entry.Status})
+			return
+		}
+		writeJSON(w, http.StatusNotFound, APIError{Error: "request not found"})
+		return
+	}
+	if err != nil {
+		s.log.Error("error getting response", "error", err, "request_id", id)
+		writeJSON(w, http.StatusInternalServerError, APIError{Error: "internal error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleWallets(w http.ResponseWriter, r *http.Request) {
+	if !authorized(r, s.config().APIToken) {
+		writeJSON(w, http.StatusUnauthorized, APIError{Error: "unauthorized"})
+		return
+	}
+	balances := make([]client.WalletBalance, 0)
+	for _, chain := range s.chains() {
+		chainBalances, err := s.clients[chain].WalletBalances(r.Context(), s.chainContracts(chain)...)
+		if err != nil {
+			s.log.Error("error fetching wallet balances", "error", err, "chain", chain)
+			writeJSON(w, http.StatusBadGateway, APIError{Error: "error fetching wallet balances"})
+			return
+		}
+		balances = append(balances, chainBalances...)
+	}
+	writeJSON(w, http.StatusOK, balances)
+}
+
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if !authorized(r, s.config().AdminToken) {
+		writeJSON(w, http.StatusUnauthorized, APIError{Error: "unauthorized"})
+		return
+	}
+	query := r.URL.Query()
+	limit := 100
+	if l := query.Get("limit"); l != "" {
+		parsed, err := strconv.Atoi(l)
+		if err != nil || parsed <= 0 {
+			writeJSON(w, http.StatusBadRequest, APIError{Error: "invalid limit"})
+			return
+		}
+		limit = parsed
+	}
+	var (
+		records []*HistoryRecord
+		err     error
+	)
+	switch {
+	case query.Get("user") != "":
+		records, err = s.UserHistory(query.Get("user"), limit)
+	case query.Get("address") != "":
+		records, err = s.AddressHistory(query.Get("address"), limit)
+	case query.Get("channel") != "":
+		records, err = s.ChannelHistory(query.Get("channel"), limit)
+	default:
+		records, err = s.RecentHistory(limit)
+	}
+	if err != nil {
+		s.log.Error("error querying history", "error", err)
+		writeJSON(w, http.StatusInternalServerError, APIError{Error: "internal error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, records)
+}
+
+func (s *Server) handleHistoryTotals(w http.ResponseWriter, r *http.Request) {
+	if !authorized(r, s.config().AdminToken) {
+		writeJSON(w, http.StatusUnauthorized, APIError{Error: "unauthorized"})
+		return
+	}
+	days := 30
+	if d := r.URL.Query().Get("days"); d != "" {
+		parsed, err := strconv.Atoi(d)
+		if err != nil || parsed <= 0 {
+			writeJSON(w, http.StatusBadRequest, APIError{Error: "invalid days"})
+			return
+		}
+		days = parsed
+	}
+	since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -(days - 1))
+	totals, err := s.DailyTotals(since)
+	if err != nil {
+		s.log.Error("error querying history totals", "error", err)
+		writeJSON(w, http.StatusInternalServerError, APIError{Error: "internal error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, totals)
+}
+
+// apiHandler returns the routes of the API
+func (s *Server) apiHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/requests", s.handleCreateRequest)
+	mux.HandleFunc("GET /v1/requests/{id}", s.handleGetRequest)
+	mux.HandleFunc("GET /v1/wallets", s.handleWallets)
+	mux.HandleFunc("GET /v1/history", s.handleHistory)
+	mux.HandleFunc("GET /v1/history/totals", s.handleHistoryTotals)
+	return mux
+}
+
+func (s *Server) serveAPI(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:              fmt.Sprintf(":%d", s.config().Port),
+		Handler:           s.apiHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err := srv.Shutdown(shutdownCtx)
+		if err != nil {
+			s.log.Error("error shutting down api server", "error", err)
+		}
+	}()
+
+	s.log.Info("starting api server", "port", s.config().Port)
+	err := srv.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
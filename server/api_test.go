@@ -0,0 +1,183 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/public-awesome/faucet/client"
+	"github.com/public-awesome/faucet/config"
+)
+
+func testServer(cfg *config.Config) *Server {
+	s := &Server{
+		store:   NewMemoryStore(),
+		log:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		paused:  make(map[string]bool),
+		pending: make(map[string]chan *SendResponse),
+	}
+	s.currentConfig.Store(cfg)
+	return s
+}
+
+func TestAPIAuthorization(t *testing.T) {
+	r := httptest.NewRequest("GET", "/v1/wallets", nil)
+	if authorized(r, "") {
+		t.Fatal("expected requests to be refused without a token")
+	}
+	r.Header.Set("Authorization", "Bearer wrong")
+	if authorized(r, "secret") {
+		t.Fatal("expected a wrong token to be refused")
+	}
+	r.Header.Set("Authorization", "Bearer secret")
+	if !authorized(r, "secret") {
+		t.Fatal("expected the token to be accepted")
+	}
+	if authorized(r, "admin") {
+		t.Fatal("expected the api token to be refused as the admin token")
+	}
+}
+
+func TestRemoteIP(t *testing.T) {
+	s := testServer(&config.Config{APITrustedProxies: []string{"10.0.0.0/8", "127.0.0.1"}})
+	for _, tc := range []struct {
+		name, remoteAddr, forwarded, expected string
+	}{
+		{"direct client", "203.0.113.7:1234", "", "203.0.113.7"},
+		{"spoofed header from untrusted client", "203.0.113.7:1234", "198.51.100.1", "203.0.113.7"},
+		{"trusted proxy", "127.0.0.1:1234", "198.51.100.1", "198.51.100.1"},
+		{"client prepends addresses", "127.0.0.1:1234", "1.1.1.1, 198.51.100.1, 10.1.2.3", "198.51.100.1"},
+		{"proxy without header", "10.0.0.1:1234", "", "10.0.0.1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/v1/requests", nil)
+			r.RemoteAddr = tc.remoteAddr
+			if tc.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tc.forwarded)
+			}
+			if ip := s.remoteIP(r); ip != tc.expected {
+				t.Fatalf("expected %s, got %s", tc.expected, ip)
+			}
+		})
+	}
+}
+
+func TestSweepResponses(t *testing.T) {
+	s := testServer(&config.Config{})
+	s.saveResponse(&SendResponse{ID: "recent", Success: true})
+	for id, expiresAt := range map[string]time.Time{"expired": time.Now().Add(-time.Minute), "legacy": {}} {
+		b, err := json.Marshal(responseEntry{SendResponse: &SendResponse{ID: id}, ExpiresAt: expiresAt})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = s.store.Set([]byte(responseKey(id)), b)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reclaimed, err := s.sweepResponses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reclaimed != 2 {
+		t.Fatalf("expected 2 responses to be swept, got %d", reclaimed)
+	}
+	response, err := s.getResponse("recent")
+	if err != nil || !response.Success {
+		t.Fatalf("expected the recent response to be kept, got %+v, %v", response, err)
+	}
+}
+
+func TestAPIHandlers(t *testing.T) {
+	s := testServer(&config.Config{
+		APIToken:   "secret",
+		AdminToken: "admin",
+		APIChannel: "faucet",
+		Channels:   []config.ChannelProfile{{Name: "faucet", Coins: "10ustars"}},
+	})
+	s.clients = map[string]*client.Client{"": newClient(t, client.WithAccountPrefix("stars"), client.WithFaucetMnemonics(testMnemonic))}
+	s.requests = make(chan *SendRequest, 10)
+	// the processor answers every request with a sent transaction
+	go func() {
+		for req := range s.requests {
+			response := &SendResponse{ID: req.ID, Source: req.Source, Channel: req.Channel, Address: req.Address, Amount: req.Amount, TxHash: "AB", Success: true}
+			s.recordHistory(response)
+			s.saveResponse(response)
+		}
+	}()
+	defer close(s.requests)
+	addresses := batchRequests(t, 2)
+	handler := s.apiHandler()
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.RemoteAddr = "203.0.113.7:1234"
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	for _, tc := range []struct {
+		name, method, path, token, body string
+		status                          int
+	}{
+		{"no token", "POST", "/v1/requests", "", `{"address":"` + addresses[0].Address + `"}`, http.StatusUnauthorized},
+		{"invalid body", "POST", "/v1/requests", "secret", `{`, http.StatusBadRequest},
+		{"unknown channel", "POST", "/v1/requests", "secret", `{"address":"` + addresses[0].Address + `","channel":"other"}`, http.StatusBadRequest},
+		{"invalid address", "POST", "/v1/requests", "secret", `{"address":"osmo1abc"}`, http.StatusBadRequest},
+		{"sent", "POST", "/v1/requests", "secret", `{"address":"` + addresses[0].Address + `"}`, http.StatusOK},
+		{"cooldown", "POST", "/v1/requests", "secret", `{"address":"` + addresses[1].Address + `"}`, http.StatusTooManyRequests},
+		{"unknown request", "GET", "/v1/requests/missing", "secret", "", http.StatusNotFound},
+		{"history without admin token", "GET", "/v1/history", "secret", "", http.StatusUnauthorized},
+		{"invalid history limit", "GET", "/v1/history?limit=0", "admin", "", http.StatusBadRequest},
+		{"invalid totals days", "GET", "/v1/history/totals?days=x", "admin", "", http.StatusBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := do(tc.method, tc.path, tc.token, tc.body)
+			if w.Code != tc.status {
+				t.Fatalf("expected status %d, got %d: %s", tc.status, w.Code, w.Body)
+			}
+		})
+	}
+
+	// the cooldown is tracked by address and client ip, the second address was blocked by the ip
+	w := do("GET", "/v1/history?address="+addresses[0].Address, "admin", "")
+	var records []*HistoryRecord
+	if err := json.NewDecoder(w.Body).Decode(&records); err != nil || len(records) != 1 {
+		t.Fatalf("expected the sent request in the history, got %v, %v", records, err)
+	}
+	w = do("GET", "/v1/requests/"+records[0].ID, "secret", "")
+	var response SendResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil || w.Code != http.StatusOK || response.TxHash != "AB" {
+		t.Fatalf("expected the stored response, got %d %+v, %v", w.Code, response, err)
+	}
+	w = do("GET", "/v1/history/totals?days=1", "admin", "")
+	var totals map[string]sdk.Coins
+	if err := json.NewDecoder(w.Body).Decode(&totals); err != nil || len(totals) != 1 || totals[records[0].CreatedAt.Format(time.DateOnly)].String() != "10ustars" {
+		t.Fatalf("expected the totals of today, got %v, %v", totals, err)
+	}
+
+	err := s.setQueueStatus(&SendRequest{ID: "queued"}, StatusQueued)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = do("GET", "/v1/requests/queued", "secret", "")
+	var pending APIPendingResponse
+	if err := json.NewDecoder(w.Body).Decode(&pending); err != nil || w.Code != http.StatusAccepted || pending.Status != StatusQueued {
+		t.Fatalf("expected the queued status, got %d %+v, %v", w.Code, pending, err)
+	}
+
+	s.paused["faucet"] = true
+	if w = do("POST", "/v1/requests", "secret", `{"address":"`+addresses[1].Address+`"}`); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected a paused channel to refuse requests, got %d", w.Code)
+	}
+}
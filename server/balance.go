@@ -0,0 +1,237 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/public-awesome/faucet/client"
+)
+
+// faucetEmptyError is the error of requests that were not sent because no wallet can cover them
+const faucetEmptyError = "faucet is empty"
+
+// covered returns whether any of the wallets can cover the amount plus fees. In authz mode the native
+// coins are sent by the granter so they must be within the remaining limit of the wallet instead
+func (s *Server) covered(ctx context.Context, c *client.Client, balances []client.WalletBalance, amount string) (bool, error) {
+	coins, err := client.ParseCoins(amount)
+	if err != nil {
+		return false, err
+	}
+	fee, err := c.EstimatedFee(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, wallet := range balances {
+		required, limit := coins.Add(fee), sdk.NewCoins()
+		if wallet.AuthzGranter != "" {
+			required = sdk.NewCoins(fee)
+			for _, coin := range coins {
+				if _, ok := client.CW20Contract(coin.Denom); ok {
+					required = required.Add(coin)
+				} else {
+					limit = limit.Add(coin)
+				}
+			}
+		}
+		if wallet.Balances.IsAllGTE(required) && wallet.AuthzLimit.IsAllGTE(limit) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// deduct returns the balances left in each wallet after sending the amount from it. In authz mode the
+// native coins are deducted from the remaining limit since the granter sends them
+func deduct(balances []client.WalletBalance, amount string) []client.WalletBalance {
+	coins, err := client.ParseCoins(amount)
+	if err != nil {
+		return balances
+	}
+	remaining := make([]client.WalletBalance, 0, len(balances))
+	for _, wallet := range balances {
+		spent, limited := coins, sdk.NewCoins()
+		if wallet.AuthzGranter != "" {
+			spent = sdk.NewCoins()
+			for _, coin := range coins {
+				if _, ok := client.CW20Contract(coin.Denom); ok {
+					spent = spent.Add(coin)
+				} else {
+					limited = limited.Add(coin)
+				}
+			}
+		}
+		wallet.Balances = subtractCoins(wallet.Balances, spent)
+		wallet.AuthzLimit = subtractCoins(wallet.AuthzLimit, limited)
+		remaining = append(remaining, wallet)
+	}
+	return remaining
+}
+
+// subtractCoins subtracts b from a, denoms that would go negative are dropped
+func subtractCoins(a, b sdk.Coins) sdk.Coins {
+	result := sdk.NewCoins()
+	for _, coin := range a {
+		if left := coin.Amount.Sub(b.AmountOf(coin.Denom)); left.IsPositive() {
+			result = result.Add(sdk.NewCoin(coin.Denom, left))
+		}
+	}
+	return result
+}
+
+// updateChannel pauses or resumes a channel depending on whether the wallets can cover its amount
+func (s *Server) updateChannel(ctx context.Context, c *client.Client, channel, amount string, balances []client.WalletBalance) bool {
+	ok, err := s.covered(ctx, c, balances, amount)
+	if err != nil {
+		s.log.Error("error checking channel balance", "error", err, "channel", channel, "amount", amount)
+		return true
+	}
+	s.pausedMu.Lock()
+	wasPaused := s.paused[channel]
+	if ok {
+		delete(s.paused, channel)
+	} else {
+		s.paused[channel] = true
+	}
+	s.pausedMu.Unlock()
+
+	if !ok && !wasPaused {
+		s.log.Warn("pausing channel, faucet balance is too low", "channel", channel, "amount", amount)
+		s.alert(fmt.Sprintf("Faucet channel `%s` is paused, no wallet can cover `%s` plus fees. Wallets: %s", channel, amount, formatBalances(balances)))
+	}
+	if ok && wasPaused {
+		s.log.Info("resuming channel, faucet balance recovered", "channel", channel, "amount", amount)
+		s.alert(fmt.Sprintf("Faucet channel `%s` has resumed", channel))
+	}
+	return ok
+}
+
+// cw20Contracts returns the CW20 contracts of the amounts
+func cw20Contracts(amounts ...string) []string {
+	seen := make(map[string]bool)
+	var contracts []string
+	for _, amount := range amounts {
+		coins, err := client.ParseCoins(amount)
+		if err != nil {
+			continue
+		}
+		for _, coin := range coins {
+			if contract, ok := client.CW20Contract(coin.Denom); ok && !seen[contract] {
+				seen[contract] = true
+				contracts = append(contracts, contract)
+			}
+		}
+	}
+	return contracts
+}
+
+// chainContracts returns the CW20 contracts handed out by the channels of the chain
+func (s *Server) chainContracts(chain string) []string {
+	var contracts []string
+	for _, denom := range s.config().Denoms(chain) {
+		if contract, ok := client.CW20Contract(denom); ok {
+			contracts = append(contracts, contract)
+		}
+	}
+	return contracts
+}
+
+func (s *Server) isPaused(channel string) bool {
+	s.pausedMu.Lock()
+	defer s.pausedMu.Unlock()
+	return s.paused[channel]
+}
+
+// canSend checks the wallet balances right before a send, if they can't be fetched the send is attempted anyway
+func (s *Server) canSend(ctx context.Context, c *client.Client, req *SendRequest) bool {
+	balances, err := c.WalletBalances(ctx, cw20Contracts(req.Amount)...)
+	if err != nil {
+		s.log.Error("error fetching wallet balances", "error", err)
+		return true
+	}
+	return s.updateChannel(ctx, c, req.Channel, req.Amount, balances)
+}
+
+// monitorBalances periodically checks that every channel can be covered by the wallets
+func (s *Server) monitorBalances(ctx context.Context) {
+	ticker := time.NewTicker(s.config().BalanceCheckInterval)
+	defer ticker.Stop()
+	for {
+		for _, chain := range s.chains() {
+			c := s.clients[chain]
+			balances, err := c.WalletBalances(ctx, s.chainContracts(chain)...)
+			if err != nil {
+				s.log.Error("error fetching wallet balances", "error", err, "chain", chain)
+				continue
+			}
+			for _, profile := range s.config().Channels {
+				if profile.Chain == chain {
+					s.updateChannel(ctx, c, profile.Key(), profile.Coins, balances)
+				}
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			s.log.Info("stopping balance monitor")
+			return
+		}
+	}
+}
+
+func formatBalances(balances []client.WalletBalance) string {
+	var b bytes.Buffer
+	for i, wallet := range balances {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "`%s`: `%s`", wallet.Address, wallet.Balances.String())
+		if wallet.AuthzGranter != "" {
+			limit := wallet.AuthzLimit.String()
+			if limit == "" {
+				limit = "none"
+			}
+			fmt.Fprintf(&b, " (authz limit `%s`)", limit)
+		}
+	}
+	return b.String()
+}
+
+// alert notifies the admins through the alert channel and webhook when configured
+func (s *Server) alert(message string) {
+	if s.config().AlertChannelID != "" && s.discord != nil {
+		_, err := s.discord.ChannelMessageSend(s.config().AlertChannelID, message)
+		if err != nil {
+			s.log.Error("error sending alert", "error", err, "channel", s.config().AlertChannelID)
+		}
+	}
+	if s.config().AlertWebhookURL != "" {
+		// content is used by discord webhooks and text by slack webhooks
+		body, err := json.Marshal(map[string]string{"content": message, "text": message})
+		if err != nil {
+			s.log.Error("error encoding alert", "error", err)
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config().AlertWebhookURL, bytes.NewReader(body))
+		if err != nil {
+			s.log.Error("error creating alert request", "error", err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			s.log.Error("error sending alert webhook", "error", err)
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			s.log.Error("alert webhook returned an error", "status", resp.StatusCode)
+		}
+	}
+}
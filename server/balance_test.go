@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/public-awesome/faucet/client"
+	"github.com/public-awesome/faucet/config"
+)
+
+func TestPauseAndResume(t *testing.T) {
+	s := testServer(&config.Config{})
+	// static fees of 100000ustars per transaction
+	c := newClient(t, client.WithAccountPrefix("stars"), client.WithFaucetMnemonics(testMnemonic),
+		client.WithGasPrices("1ustars"), client.WithGasAmount(100000), client.WithFeeMode(client.FeeModeStatic, 0, ""))
+	ctx := context.Background()
+	balances := func(amount int64) []client.WalletBalance {
+		return []client.WalletBalance{{Address: "stars1a", Balances: sdk.NewCoins(sdk.NewInt64Coin("ustars", amount))}}
+	}
+
+	for _, step := range []struct {
+		name    string
+		balance int64
+		paused  bool
+	}{
+		{name: "covered", balance: 100010},
+		{name: "fees not covered", balance: 100009, paused: true},
+		{name: "still empty", balance: 0, paused: true},
+		{name: "refilled", balance: 1000000},
+	} {
+		if ok := s.updateChannel(ctx, c, "faucet", "10ustars", balances(step.balance)); ok == step.paused {
+			t.Fatalf("%s: expected the channel to be covered: %v", step.name, !step.paused)
+		}
+		if s.isPaused("faucet") != step.paused {
+			t.Fatalf("%s: expected paused to be %v", step.name, step.paused)
+		}
+	}
+
+	// in authz mode the amount comes out of the remaining limit and the wallet only pays the fees
+	authz := func(limit int64) []client.WalletBalance {
+		wallets := balances(100000)
+		wallets[0].AuthzGranter = "stars1granter"
+		wallets[0].AuthzLimit = sdk.NewCoins(sdk.NewInt64Coin("ustars", limit))
+		return wallets
+	}
+	if !s.updateChannel(ctx, c, "faucet", "10ustars", authz(10)) {
+		t.Fatal("expected the remaining limit to cover the amount")
+	}
+	if s.updateChannel(ctx, c, "faucet", "10ustars", authz(9)) || !s.isPaused("faucet") {
+		t.Fatal("expected the channel to be paused once the limit is exhausted")
+	}
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/public-awesome/faucet/client"
+)
+
+// collectBatch gathers the pending requests of the queue that arrive within the batch window, up to the batch size
+func (s *Server) collectBatch(ctx context.Context, queue <-chan *SendRequest, first *SendRequest) []*SendRequest {
+	batch := []*SendRequest{first}
+	timer := time.NewTimer(s.config().BatchWindow)
+	defer timer.Stop()
+	for len(batch) < s.config().BatchSize {
+		select {
+		case req := <-queue:
+			batch = append(batch, req)
+		case <-timer.C:
+			return batch
+		case <-ctx.Done():
+			return batch
+		}
+	}
+	return batch
+}
+
+// processBatch sends the requests of each chain in a single transaction
+func (s *Server) processBatch(ctx context.Context, batch []*SendRequest) {
+	var chains []string
+	byChain := make(map[string][]*SendRequest)
+	for _, req := range batch {
+		// ibc transfers are tracked by transaction and fee grants may renew an allowance of a given
+		// wallet so they are never batched
+		if req.IBC != nil || req.FeeGrant != nil {
+			s.processRequest(ctx, req)
+			continue
+		}
+		if _, ok := byChain[req.Chain]; !ok {
+			chains = append(chains, req.Chain)
+		}
+		byChain[req.Chain] = append(byChain[req.Chain], req)
+	}
+	for _, chain := range chains {
+		s.processChainBatch(ctx, chain, byChain[chain])
+	}
+}
+
+// processChainBatch sends all the requests in a single transaction, if it definitely failed each
+// request is sent on its own so one bad recipient doesn't fail the rest. When the outcome of the batch
+// is unknown, for example after a broadcast timeout, the requests are reported as pending with the
+// batch hash since sending them again could pay the recipients twice
+func (s *Server) processChainBatch(ctx context.Context, chain string, batch []*SendRequest) {
+	unprocessed := make([]*SendRequest, 0, len(batch))
+	for _, req := range batch {
+		if s.startProcessing(ctx, req) {
+			unprocessed = append(unprocessed, req)
+		}
+	}
+	batch = unprocessed
+
+	c, err := s.chainClient(chain)
+	if err != nil {
+		for _, req := range batch {
+			s.responses <- newSendResponse(req, nil, err)
+		}
+		return
+	}
+	amounts := make([]string, 0, len(batch))
+	for _, req := range batch {
+		amounts = append(amounts, req.Amount)
+	}
+	// requests the wallets can cover on their own but not on top of the rest of the batch are sent
+	// individually afterwards, they are checked again against the balances left by the batch
+	var overflow []*SendRequest
+	balances, err := c.WalletBalances(ctx, cw20Contracts(amounts...)...)
+	if err != nil {
+		s.log.Error("error fetching wallet balances", "error", err)
+	} else {
+		coverable := make([]*SendRequest, 0, len(batch))
+		remaining := balances
+		for _, req := range batch {
+			if !s.updateChannel(ctx, c, req.Channel, req.Amount, balances) {
+				s.responses <- newSendResponse(req, nil, errors.New(faucetEmptyError))
+				continue
+			}
+			ok, err := s.covered(ctx, c, remaining, req.Amount)
+			if err == nil && !ok {
+				overflow = append(overflow, req)
+				continue
+			}
+			coverable = append(coverable, req)
+			remaining = deduct(remaining, req.Amount)
+		}
+		batch = coverable
+	}
+	defer func() {
+		for _, req := range overflow {
+			s.processRequest(ctx, req)
+		}
+	}()
+	if len(batch) == 0 {
+		return
+	}
+
+	sends := make([]client.Send, 0, len(batch))
+	for _, req := range batch {
+		s.log.Info("processing request", "request_id", req.ID, "channel", req.ChannelName, "user", req.User, "user_id", req.UserID, "amount", req.Amount, "address", req.Address, "batch_size", len(batch))
+		sends = append(sends, client.Send{Address: req.Address, Amount: req.Amount})
+	}
+
+	result, err := c.BankMultiSend(s.withTxHashes(ctx, batch...), sends)
+	if err == nil || !client.TxFailed(result, err) {
+		if err != nil {
+			s.log.Error("error sending batch, the transaction may still be included", "error", err, "tx_hash", result.TxHash, "batch_size", len(batch))
+		}
+		for _, req := range batch {
+			s.responses <- newSendResponse(req, result, err)
+		}
+		return
+	}
+	if len(batch) > 1 {
+		s.log.Error("error sending batch, falling back to individual sends", "error", err, "batch_size", len(batch))
+	}
+
+	for _, req := range batch {
+		if len(batch) > 1 {
+			result, err = c.BankSend(s.withTxHashes(ctx, req), req.Address, req.Amount)
+		}
+		if err != nil {
+			s.log.Error("error sending request", "error", err, "request_id", req.ID)
+		}
+		s.responses <- newSendResponse(req, result, err)
+	}
+}
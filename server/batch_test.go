@@ -0,0 +1,214 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/public-awesome/faucet/client"
+	"github.com/public-awesome/faucet/config"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+// fakeChain serves the REST endpoints and the broadcast_tx_sync RPC used by the client, broadcast
+// decides the outcome of each broadcast from the number of bank sends of the transaction
+type fakeChain struct {
+	*httptest.Server
+	mu         sync.Mutex
+	broadcasts int
+	broadcast  func(n int) (code uint32, ok bool)
+	// txs are the codes of the transactions included in a block by hash
+	txs map[string]uint32
+	// balances replace the default balance of the addresses
+	balances map[string]sdk.Coins
+}
+
+func newFakeChain(t *testing.T, broadcast func(n int) (uint32, bool)) *fakeChain {
+	chain := &fakeChain{broadcast: broadcast, txs: make(map[string]uint32), balances: make(map[string]sdk.Coins)}
+	chain.Server = httptest.NewServer(http.HandlerFunc(chain.serve))
+	t.Cleanup(chain.Close)
+	return chain
+}
+
+func (f *fakeChain) serve(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/cosmos/auth/v1beta1/account_info/"):
+		fmt.Fprint(w, `{"info":{"account_number":"1","sequence":"1"}}`)
+	case strings.HasPrefix(r.URL.Path, "/cosmos/bank/v1beta1/balances/"):
+		f.mu.Lock()
+		balances, ok := f.balances[strings.TrimPrefix(r.URL.Path, "/cosmos/bank/v1beta1/balances/")]
+		f.mu.Unlock()
+		if !ok {
+			balances = sdk.NewCoins(sdk.NewInt64Coin("ustars", 1000000000))
+		}
+		_ = json.NewEncoder(w).Encode(map[string]sdk.Coins{"balances": balances})
+	case strings.HasPrefix(r.URL.Path, "/cosmos/tx/v1beta1/txs/"):
+		f.mu.Lock()
+		code, ok := f.txs[strings.TrimPrefix(r.URL.Path, "/cosmos/tx/v1beta1/txs/")]
+		f.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"tx_response":{"height":"10","code":%d,"gas_used":"50000"}}`, code)
+	case r.Method == http.MethodPost:
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params struct {
+				Tx []byte `json:"tx"`
+			} `json:"params"`
+		}
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &req)
+		f.mu.Lock()
+		f.broadcasts++
+		f.mu.Unlock()
+		code, ok := f.broadcast(strings.Count(string(req.Params.Tx), "/cosmos.bank.v1beta1.MsgSend"))
+		if !ok {
+			http.Error(w, "node unavailable", http.StatusBadGateway)
+			return
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":{"code":%d,"data":"","log":"failed","codespace":"","hash":"AB"}}`, req.ID, code)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeChain) include(txHash string, code uint32) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.txs[txHash] = code
+}
+
+func (f *fakeChain) setBalance(address string, coins sdk.Coins) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.balances[address] = coins
+}
+
+func (f *fakeChain) broadcastCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.broadcasts
+}
+
+func (f *fakeChain) client(t *testing.T, opts ...client.ClientOption) *client.Client {
+	return newClient(t, append([]client.ClientOption{client.WithRPC(f.URL), client.WithAPI(f.URL), client.WithAccountPrefix("stars"),
+		client.WithFaucetMnemonics(testMnemonic), client.WithChainID("elgafar-1"), client.WithGasPrices("1ustars"),
+		client.WithGasAmount(100000), client.WithFeeMode("static", 0, "")}, opts...)...)
+}
+
+func newClient(t *testing.T, opts ...client.ClientOption) *client.Client {
+	t.Helper()
+	c, err := client.New(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func batchRequests(t *testing.T, n int) []*SendRequest {
+	addresses := newClient(t, client.WithAccountPrefix("stars"), client.WithFaucetMnemonics(testMnemonic), client.WithAccounts(uint32(n+1))).Addresses()
+	batch := make([]*SendRequest, 0, n)
+	for i := 0; i < n; i++ {
+		batch = append(batch, &SendRequest{ID: fmt.Sprintf("req-%d", i), Channel: "faucet", Amount: "10ustars", Address: addresses[i+1]})
+	}
+	return batch
+}
+
+func TestBatchFallback(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		// broadcast is the outcome of a broadcast with n bank sends
+		broadcast  func(n int) (uint32, bool)
+		broadcasts int
+		success    bool
+		pending    bool
+	}{
+		{
+			name:       "sent",
+			broadcast:  func(int) (uint32, bool) { return 0, true },
+			broadcasts: 1,
+			success:    true,
+		},
+		{
+			name: "rejected batch is sent individually",
+			broadcast: func(n int) (uint32, bool) {
+				if n > 1 {
+					return 5, true
+				}
+				return 0, true
+			},
+			broadcasts: 4,
+			success:    true,
+		},
+		{
+			name:       "unknown outcome is not sent again",
+			broadcast:  func(int) (uint32, bool) { return 0, false },
+			broadcasts: 1,
+			pending:    true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			chain := newFakeChain(t, tc.broadcast)
+			s := testServer(&config.Config{})
+			s.clients = map[string]*client.Client{"": chain.client(t)}
+			s.responses = make(chan *SendResponse, 10)
+
+			s.processChainBatch(context.Background(), "", batchRequests(t, 3))
+			if broadcasts := chain.broadcastCount(); broadcasts != tc.broadcasts {
+				t.Fatalf("expected %d broadcasts, got %d", tc.broadcasts, broadcasts)
+			}
+			for i := 0; i < 3; i++ {
+				response := <-s.responses
+				if response.Success != tc.success || response.Pending != tc.pending {
+					t.Fatalf("unexpected response %+v", response)
+				}
+			}
+		})
+	}
+}
+
+func TestBatchBalance(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		sizes []int
+	)
+	chain := newFakeChain(t, func(n int) (uint32, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		sizes = append(sizes, n)
+		return 0, true
+	})
+	c := chain.client(t)
+	// the fees are 100000ustars so the wallet covers two requests of 10ustars but not three
+	chain.setBalance(c.Addresses()[0], sdk.NewCoins(sdk.NewInt64Coin("ustars", 100025)))
+	s := testServer(&config.Config{})
+	s.clients = map[string]*client.Client{"": c}
+	s.responses = make(chan *SendResponse, 10)
+
+	s.processChainBatch(context.Background(), "", batchRequests(t, 3))
+	mu.Lock()
+	defer mu.Unlock()
+	// the request left out of the batch is checked again and sent on its own
+	if len(sizes) != 2 || sizes[0] != 2 || sizes[1] != 1 {
+		t.Fatalf("expected a batch of 2 and an individual send, got %v", sizes)
+	}
+	for i := 0; i < 3; i++ {
+		if response := <-s.responses; !response.Success {
+			t.Fatalf("unexpected response %+v", response)
+		}
+	}
+	if s.isPaused("faucet") {
+		t.Fatal("expected the channel to stay open")
+	}
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CooldownEntry is the last request of an address or user in a channel, the entry can be
+// deleted once it expires
+type CooldownEntry struct {
+	LastRequest time.Time `json:"last_request"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+const cooldownPrefix = "cooldown/"
+
+// prefixes of the keys that are not cooldowns, every other key was written by the legacy cooldown
+var storePrefixes = []string{cooldownPrefix, queuePrefix, responsePrefix, "reservation-", "history/", packetPrefix}
+
+func cooldownKey(channelId, id string) string {
+	return fmt.Sprintf("%s%s-%s", cooldownPrefix, channelId, id)
+}
+
+func (s *Server) getCooldown(key string) (*CooldownEntry, error) {
+	b, err := s.store.Get([]byte(key))
+	if err != nil {
+		return nil, err
+	}
+	var entry CooldownEntry
+	err = json.Unmarshal(b, &entry)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// lastRequest returns the time of the last request of the address or user in the channel, nil if there is none
+func (s *Server) lastRequest(channelId, id string) (*time.Time, error) {
+	entry, err := s.getCooldown(cooldownKey(channelId, id))
+	if err == nil {
+		return &entry.LastRequest, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	// cooldowns written before the keys had an expiry
+	return s.getByKey(fmt.Sprintf("%s-%s", channelId, id))
+}
+
+// maxInterval returns the longest cooldown of the configured channels
+func (s *Server) maxInterval() time.Duration {
+	interval := s.channelInterval("")
+	for channel := range s.config().FaucetChannelCoins {
+		interval = max(interval, s.channelInterval(channel))
+	}
+	return interval
+}
+
+// sweepCooldowns deletes the expired cooldown keys and returns how many were deleted
+func (s *Server) sweepCooldowns() (int, error) {
+	now := time.Now()
+	batch := s.store.Batch()
+	defer batch.Close()
+	reclaimed := 0
+	s.cooldownMu.Lock()
+	defer s.cooldownMu.Unlock()
+	err := s.store.Iterate([]byte(cooldownPrefix), func(key, value []byte) error {
+		var entry CooldownEntry
+		err := json.Unmarshal(value, &entry)
+		if err != nil {
+			s.log.Error("error decoding cooldown entry", "error", err, "key", string(key))
+			return nil
+		}
+		if entry.ExpiresAt.After(now) {
+			return nil
+		}
+		reclaimed++
+		return batch.Delete(key)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return reclaimed, batch.Commit()
+}
+
+// sweepLegacyCooldowns deletes the cooldown keys written without an expiry once the longest channel
+// interval has passed, the whole store is scanned so it only runs once on startup
+func (s *Server) sweepLegacyCooldowns() (int, error) {
+	cutoff := time.Now().Add(-s.maxInterval())
+	batch := s.store.Batch()
+	defer batch.Close()
+	reclaimed := 0
+	s.cooldownMu.Lock()
+	defer s.cooldownMu.Unlock()
+	err := s.store.Iterate(nil, func(key, value []byte) error {
+		for _, prefix := range storePrefixes {
+			if strings.HasPrefix(string(key), prefix) {
+				return nil
+			}
+		}
+		lastRequest, err := bytesToTime(value)
+		if err != nil || lastRequest.After(cutoff) {
+			return nil
+		}
+		reclaimed++
+		return batch.Delete(key)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return reclaimed, batch.Commit()
+}
+
+// compact reclaims the space of the keys deleted with the prefix if the backend needs it
+func (s *Server) compact(prefix string, deleted int) {
+	if deleted == 0 {
+		return
+	}
+	if compacter, ok := s.store.(Compacter); ok {
+		err := compacter.Compact([]byte(prefix))
+		if err != nil {
+			s.log.Error("error compacting store", "error", err)
+		}
+	}
+}
+
+// sweepStore periodically deletes the expired cooldown and response keys and compacts the store
+func (s *Server) sweepStore(ctx context.Context) {
+	reclaimed, err := s.sweepLegacyCooldowns()
+	if err != nil {
+		s.log.Error("error sweeping legacy cooldowns", "error", err)
+	} else if reclaimed > 0 {
+		s.log.Info("swept legacy cooldowns", "reclaimed", reclaimed)
+	}
+
+	ticker := time.NewTicker(s.config().CooldownSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			s.log.Info("stopping store sweeper")
+			return
+		}
+		reclaimed, err := s.sweepCooldowns()
+		if err != nil {
+			s.log.Error("error sweeping cooldowns", "error", err)
+		} else {
+			s.log.Info("swept expired cooldowns", "reclaimed", reclaimed)
+			s.compact(cooldownPrefix, reclaimed)
+		}
+		reclaimed, err = s.sweepResponses()
+		if err != nil {
+			s.log.Error("error sweeping responses", "error", err)
+		} else {
+			s.log.Info("swept expired responses", "reclaimed", reclaimed)
+			s.compact(responsePrefix, reclaimed)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/public-awesome/faucet/config"
+)
+
+func TestSweepCooldowns(t *testing.T) {
+	s := &Server{
+		store: NewMemoryStore(),
+		log:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	s.currentConfig.Store(&config.Config{
+		FaucetChannelInterval: map[string]time.Duration{"faucet": time.Hour},
+	})
+
+	err := s.reserveCooldown("expired", -time.Minute, cooldownKey("guild-channel", "addr1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.reserveCooldown("active", time.Hour, cooldownKey("guild-channel", "addr2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reclaimed, err := s.sweepCooldowns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reclaimed != 1 {
+		t.Fatalf("expected 1 reclaimed key, got %d", reclaimed)
+	}
+	if block, _ := s.cooldown("guild-channel", "addr2", "user", time.Hour); !block {
+		t.Fatal("expected the active cooldown to be kept")
+	}
+
+	// legacy keys are kept until the longest channel interval has passed
+	old, _ := time.Now().Add(-6 * 24 * time.Hour).MarshalBinary()
+	recent, _ := time.Now().MarshalBinary()
+	_ = s.store.Set([]byte("guild-channel-addr3"), old)
+	_ = s.store.Set([]byte("guild-channel-addr4"), recent)
+	reclaimed, err = s.sweepLegacyCooldowns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reclaimed != 1 {
+		t.Fatalf("expected 1 reclaimed legacy key, got %d", reclaimed)
+	}
+	if block, _ := s.cooldown("guild-channel", "addr4", "user", time.Hour); !block {
+		t.Fatal("expected the legacy cooldown to be honored")
+	}
+}
+
+func TestCooldownReservation(t *testing.T) {
+	s := testServer(&config.Config{})
+	const channel = "guild-channel"
+
+	// a failed request releases its reservation so the user can retry right away
+	if block, _ := s.block("failed", channel, "addr1", "user1", time.Hour); block {
+		t.Fatal("expected the first request to pass")
+	}
+	if block, _ := s.block("second", channel, "addr1", "user2", time.Hour); !block {
+		t.Fatal("expected the reserved address to be blocked")
+	}
+	s.releaseCooldown("failed")
+	if block, _ := s.cooldown(channel, "addr1", "user1", time.Hour); block {
+		t.Fatal("expected the released cooldown to be deleted")
+	}
+
+	// a successful request keeps its cooldown and releasing it afterwards has no effect
+	if block, _ := s.block("sent", channel, "addr1", "user1", time.Hour); block {
+		t.Fatal("expected the request after the release to pass")
+	}
+	s.commitCooldown("sent")
+	s.releaseCooldown("sent")
+	if block, wait := s.cooldown(channel, "addr2", "user1", time.Hour); !block || wait <= 0 || wait > time.Hour {
+		t.Fatalf("expected the committed cooldown to block the user, got %v, %s", block, wait)
+	}
+
+	// releasing restores the cooldown that existed before the reservation
+	s.cooldownMu.Lock()
+	err := s.reserveCooldown("retry", time.Hour, cooldownKey(channel, "addr1"))
+	s.cooldownMu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.releaseCooldown("retry")
+	if block, _ := s.cooldown(channel, "addr1", "user3", time.Hour); !block {
+		t.Fatal("expected the previous cooldown to be restored")
+	}
+}
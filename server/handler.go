@@ -3,15 +3,18 @@ package server
 import (
 	"context"
 	"fmt"
-	"log"
 	"strings"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
+	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/gofrs/uuid"
+	"github.com/public-awesome/faucet/client"
+	"github.com/public-awesome/faucet/config"
 )
 
-func parts(message, accountPrefix string) []string {
+// parts returns the command and the address of a legacy request, the address is validated with the request
+func parts(message string) []string {
 	message = strings.TrimSpace(message)
 	parts := strings.Split(message, " ")
 	filteredPars := make([]string, 0, len(parts))
@@ -22,16 +25,12 @@ func parts(message, accountPrefix string) []string {
 		}
 		filteredPars = append(filteredPars, part)
 	}
-	if len(filteredPars) < 2 || filteredPars[0] != "$request" || !strings.HasPrefix(filteredPars[1], accountPrefix) {
+	if len(filteredPars) < 2 || filteredPars[0] != "$request" {
 		return nil
 	}
 	return filteredPars
 }
 
-func timetoBytes(t time.Time) ([]byte, error) {
-	return t.MarshalBinary()
-}
-
 func bytesToTime(b []byte) (time.Time, error) {
 	var t time.Time
 	err := t.UnmarshalBinary(b)
@@ -53,15 +52,13 @@ func (s *Server) getByKey(key string) (*time.Time, error) {
 
 }
 
-func (s *Server) block(channelId, address, author string, waitPeriod time.Duration) (bool, time.Duration) {
+// cooldown returns whether the address or author are still waiting for the period to pass and the remaining time
+func (s *Server) cooldown(channelId, address, author string, waitPeriod time.Duration) (bool, time.Duration) {
 
 	// track by recipient and by discord user id
-	addressKey := fmt.Sprintf("%s-%s", channelId, address)
-	authorKey := fmt.Sprintf("%s-%s", channelId, author)
-
-	lastRequestByAddress, err := s.getByKey(addressKey)
-	if err != nil && err != ErrNotFound {
-		s.log.Error("error getting address key", "error", err, "address_key", addressKey)
+	lastRequestByAddress, err := s.lastRequest(channelId, address)
+	if err != nil {
+		s.log.Error("error getting address key", "error", err, "address_key", cooldownKey(channelId, address))
 	}
 
 	if lastRequestByAddress != nil && time.Since(*lastRequestByAddress) < waitPeriod {
@@ -69,23 +66,132 @@ func (s *Server) block(channelId, address, author string, waitPeriod time.Durati
 		return true, waitPeriod
 	}
 
-	lastRequestByAuthor, err := s.getByKey(authorKey)
-	if err != nil && err != ErrNotFound {
-		s.log.Error("error getting author key", "error", err, "author_key", authorKey)
+	lastRequestByAuthor, err := s.lastRequest(channelId, author)
+	if err != nil {
+		s.log.Error("error getting author key", "error", err, "author_key", cooldownKey(channelId, author))
 	}
 	if lastRequestByAuthor != nil && time.Since(*lastRequestByAuthor) < waitPeriod {
 		waitPeriod = waitPeriod - time.Since(*lastRequestByAuthor)
 		return true, waitPeriod
 	}
-	now, err := timetoBytes(time.Now())
+	return false, 0
+}
+
+// block checks the cooldown and reserves it for the request, the reservation is provisional until
+// the request succeeds and is released if it fails
+func (s *Server) block(requestID, channelId, address, author string, waitPeriod time.Duration) (bool, time.Duration) {
+	s.cooldownMu.Lock()
+	defer s.cooldownMu.Unlock()
+	if block, waitTime := s.cooldown(channelId, address, author, waitPeriod); block {
+		return true, waitTime
+	}
+	err := s.reserveCooldown(requestID, waitPeriod, cooldownKey(channelId, address), cooldownKey(channelId, author))
 	if err != nil {
-		s.log.Error("error getting now", "error", err)
-		return false, 0
+		s.log.Error("error reserving cooldown", "error", err, "request_id", requestID)
 	}
-	s.store.Set([]byte(addressKey), now)
-	s.store.Set([]byte(authorKey), now)
 	return false, 0
 }
+
+func (s *Server) channelInterval(channel string) time.Duration {
+	faucetInterval, ok := s.config().FaucetChannelInterval[channel]
+	if !ok {
+		faucetInterval = time.Hour * 24 * 5
+	}
+	return faucetInterval
+}
+
+// channelProfile returns the profile of a discord channel
+func (s *Server) channelProfile(channel *discordgo.Channel) (config.ChannelProfile, bool) {
+	return s.config().LookupChannel(channel.GuildID, channel.ID, channel.Name)
+}
+
+// explorerURL returns the explorer of the channel, then of its chain and last FAUCET_EXPLORER_URL
+func (s *Server) explorerURL(channel string) string {
+	profile, ok := s.config().Channel(channel)
+	if !ok {
+		return s.config().ExplorerURL
+	}
+	if profile.ExplorerURL != "" {
+		return profile.ExplorerURL
+	}
+	if chain, ok := s.config().Chain(profile.Chain); ok {
+		return chain.ExplorerURL
+	}
+	return s.config().ExplorerURL
+}
+
+// addressPrefix returns the account prefix of the recipients of a channel
+func (s *Server) addressPrefix(profile config.ChannelProfile) string {
+	if profile.IBC != nil {
+		return profile.IBC.Prefix
+	}
+	chain, _ := s.config().Chain(profile.Chain)
+	return chain.ClientConfig.AccountPrefix
+}
+
+// recipientAddress validates the recipient of a request, IBC channels send to the counterparty chain so
+// the address must use its prefix. 0x addresses are converted so cooldowns apply to both forms of an address
+func (s *Server) recipientAddress(c *client.Client, profile config.ChannelProfile, address string) (string, error) {
+	if profile.IBC != nil {
+		_, err := sdk.GetFromBech32(address, profile.IBC.Prefix)
+		return address, err
+	}
+	return c.NormalizeAddress(address)
+}
+
+func (s *Server) emptyMessage(channel, userID string) string {
+	if profile, ok := s.config().Channel(channel); ok && profile.Messages.Empty != "" {
+		return fmt.Sprintf("<@%s> %s", userID, profile.Messages.Empty)
+	}
+	return fmt.Sprintf("<@%s> the faucet is empty, admins have been notified", userID)
+}
+
+// newDiscordRequest validates a request made from a discord channel, when the request is rejected
+// it returns the reply for the user instead
+func (s *Server) newDiscordRequest(profile config.ChannelProfile, channel *discordgo.Channel, user *discordgo.User, address string) (*SendRequest, string) {
+	c, err := s.chainClient(profile.Chain)
+	if err != nil {
+		s.log.Error("error getting chain client", "error", err, "channel", profile.Key())
+		return nil, fmt.Sprintf("<@%s> your request has failed, please try again later", user.ID)
+	}
+	address, err = s.recipientAddress(c, profile, address)
+	if err != nil {
+		return nil, fmt.Sprintf("<@%s> invalid address, please use a valid address", user.ID)
+	}
+	if s.isPaused(profile.Key()) {
+		return nil, s.emptyMessage(profile.Key(), user.ID)
+	}
+	requestID, err := uuid.NewV7()
+	if err != nil {
+		s.log.Error("error generating uuid", "error", err)
+		return nil, fmt.Sprintf("<@%s> your request has failed, please try again later", user.ID)
+	}
+	faucetInterval := s.channelInterval(profile.Key())
+	id := fmt.Sprintf("%s-%s", channel.GuildID, channel.ID)
+	block, waitTime := s.block(requestID.String(), id, address, user.ID, faucetInterval)
+	if block {
+		return nil, fmt.Sprintf("<@%s> you can send a request again <t:%d:R>", user.ID, time.Now().Add(waitTime).UTC().Unix())
+	}
+
+	req := &SendRequest{
+		ID:          requestID.String(),
+		Source:      SourceDiscord,
+		GuildID:     channel.GuildID,
+		ChannelID:   channel.ID,
+		ChannelName: channel.Name,
+		Channel:     profile.Key(),
+		Chain:       profile.Chain,
+		IBC:         profile.IBC,
+		FeeGrant:    profile.FeeGrant,
+		User:        user.Username,
+		UserID:      user.ID,
+		Amount:      profile.Coins,
+		Address:     address,
+	}
+	s.log.Info("sending request", "request_id", req.ID, "channel", req.ChannelName, "user", req.User, "user_id", req.UserID, "amount", req.Amount, "address", req.Address)
+	return req, ""
+}
+
 func (s *Server) messageHandler(ds *discordgo.Session, message *discordgo.MessageCreate) {
 	// Ignore messages from the bot itself
 	if message.Author.ID == ds.State.User.ID {
@@ -99,13 +205,13 @@ func (s *Server) messageHandler(ds *discordgo.Session, message *discordgo.Messag
 		return
 	}
 
-	_, ok := s.config.FaucetChannelCoins[channel.Name]
+	profile, ok := s.channelProfile(channel)
 	// not configured for this channel
 	if !ok {
 		return
 	}
 
-	parts := parts(message.Content, s.config.ClientConfig.AccountPrefix)
+	parts := parts(message.Content)
 	if len(parts) == 2 && parts[0] != "$request" {
 		reply := fmt.Sprintf("<@%s> invalid request, please use the `$request <address>` command", message.Author.ID)
 		_, err = ds.ChannelMessageSend(message.ChannelID, reply)
@@ -118,49 +224,27 @@ func (s *Server) messageHandler(ds *discordgo.Session, message *discordgo.Messag
 		s.log.Info("invalid request", "request", message.Content)
 		return
 	}
-	valid := s.client.ValidAddress(parts[1])
-	if !valid {
-		reply := fmt.Sprintf("<@%s> invalid address, please use a valid address", message.Author.ID)
+	req, reply := s.newDiscordRequest(profile, channel, message.Author, parts[1])
+	if req == nil {
 		_, err = ds.ChannelMessageSend(message.ChannelID, reply)
 		if err != nil {
 			s.log.Error("error sending message", "error", err)
 		}
 		return
 	}
-	faucetInterval, ok := s.config.FaucetChannelInterval[channel.Name]
-	if !ok {
-		faucetInterval = time.Hour * 24 * 5
-	}
-	id := fmt.Sprintf("%s-%s", channel.GuildID, channel.ID)
-	block, waitTime := s.block(id, message.Author.Username, message.Author.ID, faucetInterval)
-	if block {
-		reply := fmt.Sprintf("<@%s> you can send a request again <t:%d:R>", message.Author.ID, time.Now().Add(waitTime).UTC().Unix())
-		_, err := ds.ChannelMessageSend(message.ChannelID, reply)
+
+	err = s.enqueue(req)
+	if err != nil {
+		s.log.Error("error queueing request", "error", err, "request_id", req.ID)
+		s.releaseCooldown(req.ID)
+		reply = fmt.Sprintf("<@%s> your request has failed, please try again later", message.Author.ID)
+		_, err = ds.ChannelMessageSend(message.ChannelID, reply)
 		if err != nil {
-			log.Printf("Error sending message: %v", err)
+			s.log.Error("error sending message", "error", err)
 		}
 		return
 	}
-	requestID, err := uuid.NewV7()
-	if err != nil {
-		s.log.Error("error generating uuid", "error", err)
-		return
-	}
-
-	req := &SendRequest{
-		ID:          requestID.String(),
-		GuildID:     channel.GuildID,
-		ChannelID:   channel.ID,
-		ChannelName: channel.Name,
-		User:        message.Author.Username,
-		UserID:      message.Author.ID,
-		Amount:      s.config.FaucetChannelCoins[channel.Name].Coins,
-		Address:     parts[1],
-	}
-	s.log.Info("sending request", "request_id", req.ID, "channel", req.ChannelName, "user", req.User, "user_id", req.UserID, "amount", req.Amount, "address", req.Address)
-
-	s.requests <- req
-	reply := fmt.Sprintf("<@%s> your request has been sent, the transaction will be broadcasted in a few seconds", message.Author.ID)
+	reply = fmt.Sprintf("<@%s> your request has been sent, the transaction will be broadcasted in a few seconds", message.Author.ID)
 	_, err = ds.ChannelMessageSend(message.ChannelID, reply)
 	if err != nil {
 		s.log.Error("error sending message", "error", err)
@@ -168,23 +252,62 @@ func (s *Server) messageHandler(ds *discordgo.Session, message *discordgo.Messag
 
 }
 
+func (s *Server) responseMessage(response *SendResponse) string {
+	explorerURL := s.explorerURL(response.Channel)
+	var success string
+	if profile, ok := s.config().Channel(response.Channel); ok && profile.Messages.Success != "" {
+		success = "\n" + profile.Messages.Success
+	}
+	switch {
+	case response.Success && response.FeeGranter != "":
+		return fmt.Sprintf("<@%s> your fee allowance was granted, use `--fee-granter %s` to pay the fees of your transactions with it, check the transaction %s/%s%s", response.UserID, response.FeeGranter, explorerURL, response.TxHash, success)
+	case response.Success && response.IBCStatus != "":
+		return fmt.Sprintf("<@%s> your transfer has been sent, check your transaction %s/%s, you'll be notified once the tokens are received by the counterparty chain%s", response.UserID, explorerURL, response.TxHash, success)
+	case response.Success && response.Confirmed:
+		return fmt.Sprintf("<@%s> your request was confirmed at height %d, check your transaction %s/%s%s", response.UserID, response.Height, explorerURL, response.TxHash, success)
+	case response.Success:
+		return fmt.Sprintf("<@%s> your request has been sent, check your transaction %s/%s%s", response.UserID, explorerURL, response.TxHash, success)
+	case response.Pending:
+		return fmt.Sprintf("<@%s> your request was sent but couldn't be confirmed yet, check your transaction %s/%s", response.UserID, explorerURL, response.TxHash)
+	case response.Error == faucetEmptyError:
+		return s.emptyMessage(response.Channel, response.UserID)
+	case response.Code != 0:
+		return fmt.Sprintf("<@%s> your request has failed on chain with code %d: %s", response.UserID, response.Code, response.RawLog)
+	default:
+		return fmt.Sprintf("<@%s> your request has failed, please try again later", response.UserID)
+	}
+}
+
 func (s *Server) processResponses(ctx context.Context, ds *discordgo.Session) {
 	for {
 		select {
 		case response := <-s.responses:
-			s.log.Info("processing response", "response_id", response.ID, "channel", response.ChannelName, "user", response.User, "user_id", response.UserID, "tx_hash", response.TxHash, "success", response.Success, "error", response.Error)
-			if response.Success {
-				reply := fmt.Sprintf("<@%s> your request has been sent, check your transaction %s/%s", response.UserID, s.config.ExplorerURL, response.TxHash)
-				_, err := ds.ChannelMessageSend(response.ChannelID, reply)
-				if err != nil {
-					s.log.Error("error sending message", "error", err)
-				}
-			} else {
-				reply := fmt.Sprintf("<@%s> your request has failed, please try again later", response.UserID)
-				_, err := ds.ChannelMessageSend(response.ChannelID, reply)
-				if err != nil {
-					s.log.Error("error sending message", "error", err)
+			s.log.Info("processing response", "response_id", response.ID, "channel", response.ChannelName, "user", response.User, "user_id", response.UserID, "tx_hash", response.TxHash, "success", response.Success, "height", response.Height, "code", response.Code, "error", response.Error)
+			s.saveResponse(response)
+			s.recordHistory(response)
+			switch {
+			case response.IBCStatus == client.PacketPending:
+				// the cooldown of ibc transfers is committed once the packet is acknowledged
+			case response.Success, response.Pending:
+				s.commitCooldown(response.ID)
+			default:
+				s.releaseCooldown(response.ID)
+			}
+			if response.Source == SourceAPI {
+				continue
+			}
+			reply := s.responseMessage(response)
+			// requests made with a slash command edit the original interaction response
+			if interaction := s.popInteraction(response.ID); interaction != nil {
+				_, err := ds.InteractionResponseEdit(interaction, &discordgo.WebhookEdit{Content: &reply})
+				if err == nil {
+					continue
 				}
+				s.log.Error("error editing interaction response", "error", err)
+			}
+			_, err := ds.ChannelMessageSend(response.ChannelID, reply)
+			if err != nil {
+				s.log.Error("error sending message", "error", err)
 			}
 		case <-ctx.Done():
 			s.log.Info("stopping response processor")
@@ -0,0 +1,19 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParts(t *testing.T) {
+	for message, expected := range map[string][]string{
+		"$request stars1abc":               {"$request", "stars1abc"},
+		"  $request   0x9858EfFD232B4033 ": {"$request", "0x9858EfFD232B4033"},
+		"$request":                         nil,
+		"hello stars1abc":                  nil,
+	} {
+		if got := parts(message); !reflect.DeepEqual(got, expected) {
+			t.Fatalf("expected %q to be parsed as %v, got %v", message, expected, got)
+		}
+	}
+}
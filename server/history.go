@@ -0,0 +1,212 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/public-awesome/faucet/client"
+)
+
+// HistoryRecord is the ledger entry of a request and its result
+type HistoryRecord struct {
+	ID          string    `json:"id"`
+	Source      string    `json:"source"`
+	GuildID     string    `json:"guild_id"`
+	ChannelID   string    `json:"channel_id"`
+	ChannelName string    `json:"channel_name"`
+	User        string    `json:"user"`
+	UserID      string    `json:"user_id"`
+	Address     string    `json:"address"`
+	Amount      string    `json:"amount"`
+	TxHash      string    `json:"tx_hash"`
+	Success     bool      `json:"success"`
+	Error       string    `json:"error"`
+	Height      int64     `json:"height"`
+	Code        uint32    `json:"code"`
+	CreatedAt   time.Time `json:"created_at"`
+	IBCStatus   string    `json:"ibc_status,omitempty"`
+}
+
+// history keys use "/" as separator since channel names may contain "-"
+const (
+	historyRecordPrefix  = "history/record/"
+	historyAddressPrefix = "history/address/"
+	historyUserPrefix    = "history/user/"
+	historyChannelPrefix = "history/channel/"
+	historyTimePrefix    = "history/time/"
+)
+
+// historyTimestamp encodes the time so index keys sort chronologically
+func historyTimestamp(t time.Time) string {
+	return fmt.Sprintf("%020d", t.UnixNano())
+}
+
+func historyIndexKeys(record *HistoryRecord) []string {
+	ts := historyTimestamp(record.CreatedAt)
+	keys := []string{
+		fmt.Sprintf("%s%s/%s/%s", historyAddressPrefix, record.Address, ts, record.ID),
+		fmt.Sprintf("%s%s/%s", historyTimePrefix, ts, record.ID),
+	}
+	if record.UserID != "" {
+		keys = append(keys, fmt.Sprintf("%s%s/%s/%s", historyUserPrefix, record.UserID, ts, record.ID))
+	}
+	channel := record.ChannelID
+	if channel == "" {
+		channel = record.ChannelName
+	}
+	if channel != "" {
+		keys = append(keys, fmt.Sprintf("%s%s/%s/%s", historyChannelPrefix, channel, ts, record.ID))
+	}
+	return keys
+}
+
+// recordHistory saves the result of a request in the ledger and its indexes
+func (s *Server) recordHistory(response *SendResponse) {
+	record := &HistoryRecord{
+		ID:          response.ID,
+		Source:      response.Source,
+		GuildID:     response.GuildID,
+		ChannelID:   response.ChannelID,
+		ChannelName: response.ChannelName,
+		User:        response.User,
+		UserID:      response.UserID,
+		Address:     response.Address,
+		Amount:      response.Amount,
+		TxHash:      response.TxHash,
+		Success:     response.Success,
+		Error:       response.Error,
+		Height:      response.Height,
+		Code:        response.Code,
+		CreatedAt:   time.Now().UTC(),
+		IBCStatus:   response.IBCStatus,
+	}
+	b, err := json.Marshal(record)
+	if err != nil {
+		s.log.Error("error encoding history record", "error", err, "request_id", record.ID)
+		return
+	}
+	// the record and its indexes are written together so queries never find a dangling index
+	batch := s.store.Batch()
+	defer batch.Close()
+	err = batch.Set([]byte(historyRecordPrefix+record.ID), b)
+	if err != nil {
+		s.log.Error("error saving history record", "error", err, "request_id", record.ID)
+		return
+	}
+	for _, key := range historyIndexKeys(record) {
+		err = batch.Set([]byte(key), []byte(record.ID))
+		if err != nil {
+			s.log.Error("error saving history index", "error", err, "request_id", record.ID, "key", key)
+			return
+		}
+	}
+	err = batch.Commit()
+	if err != nil {
+		s.log.Error("error saving history record", "error", err, "request_id", record.ID)
+	}
+}
+
+// HistoryRecord returns the ledger entry of a request
+func (s *Server) HistoryRecord(id string) (*HistoryRecord, error) {
+	b, err := s.store.Get([]byte(historyRecordPrefix + id))
+	if err != nil {
+		return nil, err
+	}
+	var record HistoryRecord
+	err = json.Unmarshal(b, &record)
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// setHistoryIBCStatus updates the packet status of an IBC transfer, the indexes don't change
+func (s *Server) setHistoryIBCStatus(id, status string) {
+	record, err := s.HistoryRecord(id)
+	if err != nil {
+		s.log.Error("error reading history record", "error", err, "request_id", id)
+		return
+	}
+	record.IBCStatus = status
+	b, err := json.Marshal(record)
+	if err != nil {
+		s.log.Error("error encoding history record", "error", err, "request_id", id)
+		return
+	}
+	err = s.store.Set([]byte(historyRecordPrefix+id), b)
+	if err != nil {
+		s.log.Error("error saving history record", "error", err, "request_id", id)
+	}
+}
+
+// historyByIndex returns the most recent records of an index, newest first
+func (s *Server) historyByIndex(prefix string, limit int) ([]*HistoryRecord, error) {
+	records := make([]*HistoryRecord, 0)
+	err := s.store.IterateReverse([]byte(prefix), func(key, value []byte) error {
+		if limit > 0 && len(records) >= limit {
+			return ErrStopIteration
+		}
+		record, err := s.HistoryRecord(string(value))
+		if err != nil {
+			return err
+		}
+		records = append(records, record)
+		return nil
+	})
+	return records, err
+}
+
+// UserHistory returns the most recent requests of a discord user
+func (s *Server) UserHistory(userID string, limit int) ([]*HistoryRecord, error) {
+	return s.historyByIndex(historyUserPrefix+userID+"/", limit)
+}
+
+// AddressHistory returns the most recent requests to an address
+func (s *Server) AddressHistory(address string, limit int) ([]*HistoryRecord, error) {
+	return s.historyByIndex(historyAddressPrefix+address+"/", limit)
+}
+
+// ChannelHistory returns the most recent requests in a channel, by id or by name for API requests
+func (s *Server) ChannelHistory(channel string, limit int) ([]*HistoryRecord, error) {
+	return s.historyByIndex(historyChannelPrefix+channel+"/", limit)
+}
+
+// RecentHistory returns the most recent requests
+func (s *Server) RecentHistory(limit int) ([]*HistoryRecord, error) {
+	return s.historyByIndex(historyTimePrefix, limit)
+}
+
+// DailyTotals returns the coins successfully sent per UTC day since the given time
+func (s *Server) DailyTotals(since time.Time) (map[string]sdk.Coins, error) {
+	totals := make(map[string]sdk.Coins)
+	sinceTimestamp := historyTimestamp(since)
+	err := s.store.IterateReverse([]byte(historyTimePrefix), func(key, value []byte) error {
+		ts, _, _ := strings.Cut(strings.TrimPrefix(string(key), historyTimePrefix), "/")
+		if ts < sinceTimestamp {
+			return ErrStopIteration
+		}
+		record, err := s.HistoryRecord(string(value))
+		if err != nil {
+			return err
+		}
+		if !record.Success {
+			return nil
+		}
+		coins, err := client.ParseCoins(record.Amount)
+		if err != nil {
+			return nil
+		}
+		nanos, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return err
+		}
+		day := time.Unix(0, nanos).UTC().Format(time.DateOnly)
+		totals[day] = totals[day].Add(coins...)
+		return nil
+	})
+	return totals, err
+}
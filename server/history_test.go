@@ -0,0 +1,70 @@
+package server
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/public-awesome/faucet/client"
+	"github.com/public-awesome/faucet/config"
+)
+
+func TestHistory(t *testing.T) {
+	s := testServer(&config.Config{})
+	for _, response := range []*SendResponse{
+		{ID: "1", UserID: "alice", Address: "stars1a", ChannelID: "faucet", Amount: "10ustars", Success: true},
+		{ID: "2", UserID: "bob", Address: "stars1b", ChannelID: "faucet", Amount: "10ustars,5uatom", Success: true},
+		{ID: "3", UserID: "alice", Address: "stars1a", ChannelName: "api", Amount: "10ustars", Error: "failed"},
+		{ID: "4", Source: SourceAPI, Address: "stars1c", ChannelName: "api", Amount: "20ustars", Success: true},
+	} {
+		s.recordHistory(response)
+		// records created in the same nanosecond would sort by id instead of creation time
+		time.Sleep(time.Millisecond)
+	}
+
+	ids := func(records []*HistoryRecord, err error) []string {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := make([]string, 0, len(records))
+		for _, record := range records {
+			ids = append(ids, record.ID)
+		}
+		return ids
+	}
+	for name, tc := range map[string]struct {
+		got  []string
+		want []string
+	}{
+		"user":    {ids(s.UserHistory("alice", 0)), []string{"3", "1"}},
+		"address": {ids(s.AddressHistory("stars1b", 0)), []string{"2"}},
+		"channel": {ids(s.ChannelHistory("api", 0)), []string{"4", "3"}},
+		"recent":  {ids(s.RecentHistory(2)), []string{"4", "3"}},
+		"prefix":  {ids(s.UserHistory("ali", 0)), []string{}},
+	} {
+		if !slices.Equal(tc.got, tc.want) {
+			t.Fatalf("%s history: expected %v, got %v", name, tc.want, tc.got)
+		}
+	}
+
+	s.setHistoryIBCStatus("4", client.PacketAcknowledged)
+	record, err := s.HistoryRecord("4")
+	if err != nil || record.IBCStatus != client.PacketAcknowledged {
+		t.Fatalf("expected the ibc status to be updated, got %+v, %v", record, err)
+	}
+
+	// failed requests are not counted
+	totals, err := s.DailyTotals(time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	today := record.CreatedAt.Format(time.DateOnly)
+	if len(totals) != 1 || totals[today].String() != "5uatom,40ustars" {
+		t.Fatalf("unexpected totals %v", totals)
+	}
+	totals, err = s.DailyTotals(time.Now())
+	if err != nil || len(totals) != 0 {
+		t.Fatalf("expected no totals after the last request, got %v, %v", totals, err)
+	}
+}
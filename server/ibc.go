@@ -0,0 +1,175 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/public-awesome/faucet/client"
+	"github.com/public-awesome/faucet/config"
+)
+
+const (
+	packetPrefix = "ibc-"
+	// packetPollInterval is how often the status of the packets is queried
+	packetPollInterval = 10 * time.Second
+	// packetRelayGrace is how long after the timeout a pending packet is still tracked, relayers
+	// can relay the acknowledgement of a packet received right before the timeout late
+	packetRelayGrace = time.Hour
+)
+
+// PacketEntry is a sent IBC transfer tracked until its packets are acknowledged or time out
+type PacketEntry struct {
+	Response *SendResponse      `json:"response"`
+	IBC      *config.ChannelIBC `json:"ibc"`
+	// Sequences are the packet sequences, they are known once the transaction is in a block
+	Sequences []uint64  `json:"sequences"`
+	Deadline  time.Time `json:"deadline"`
+}
+
+func packetKey(id string) string {
+	return fmt.Sprintf("%s%s", packetPrefix, id)
+}
+
+func (s *Server) savePacketEntry(entry *PacketEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return s.store.Set([]byte(packetKey(entry.Response.ID)), b)
+}
+
+// trackPacket persists a sent transfer and tracks it in the background, the tracker keeps its own
+// copy of the response since the response is still being processed
+func (s *Server) trackPacket(ctx context.Context, req *SendRequest, response *SendResponse) {
+	tracked := *response
+	entry := &PacketEntry{Response: &tracked, IBC: req.IBC, Deadline: time.Now().Add(req.IBC.Timeout)}
+	err := s.savePacketEntry(entry)
+	if err != nil {
+		s.log.Error("error saving packet entry", "error", err, "request_id", response.ID)
+	}
+	go s.pollPacket(ctx, entry)
+}
+
+// resumePackets tracks the transfers that were not resolved before the last shutdown
+func (s *Server) resumePackets(ctx context.Context) error {
+	return s.store.Iterate([]byte(packetPrefix), func(key, value []byte) error {
+		var entry PacketEntry
+		err := json.Unmarshal(value, &entry)
+		if err != nil {
+			s.log.Error("error decoding packet entry", "error", err, "key", string(key))
+			return nil
+		}
+		go s.pollPacket(ctx, &entry)
+		return nil
+	})
+}
+
+func (s *Server) pollPacket(ctx context.Context, entry *PacketEntry) {
+	ticker := time.NewTicker(packetPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		status, err := s.packetStatus(ctx, entry)
+		if err != nil {
+			s.log.Error("error checking packet status", "error", err, "request_id", entry.Response.ID, "tx_hash", entry.Response.TxHash)
+			continue
+		}
+		if status != client.PacketPending {
+			s.finishPacket(entry, status)
+			return
+		}
+	}
+}
+
+// packetStatus returns the status of the transfer, it's failed or timed out if any of its packets is
+func (s *Server) packetStatus(ctx context.Context, entry *PacketEntry) (string, error) {
+	c, err := s.chainClient(entry.Response.Chain)
+	if err != nil {
+		return "", err
+	}
+	if len(entry.Sequences) == 0 {
+		sequences, err := c.PacketSequences(ctx, entry.Response.TxHash)
+		// the transaction failed in the block or was dropped from the mempool
+		if errors.Is(err, client.ErrTxFailed) || (errors.Is(err, client.ErrTxNotFound) && time.Now().After(entry.Deadline)) {
+			return client.PacketFailed, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if len(sequences) == 0 {
+			return "", fmt.Errorf("tx %s has no packets", entry.Response.TxHash)
+		}
+		entry.Sequences = sequences
+		err = s.savePacketEntry(entry)
+		if err != nil {
+			s.log.Error("error saving packet entry", "error", err, "request_id", entry.Response.ID)
+		}
+	}
+	result := client.PacketAcknowledged
+	for _, sequence := range entry.Sequences {
+		status, err := c.PacketStatus(ctx, entry.IBC.SourcePort, entry.IBC.SourceChannel, entry.Response.Address, sequence)
+		if err != nil {
+			return "", err
+		}
+		switch status {
+		case client.PacketPending:
+			if time.Now().Before(entry.Deadline.Add(packetRelayGrace)) {
+				return client.PacketPending, nil
+			}
+			result = client.PacketTimedOut
+		case client.PacketFailed, client.PacketTimedOut:
+			result = status
+		case client.PacketUnknown:
+			if result == client.PacketAcknowledged {
+				result = status
+			}
+		}
+	}
+	return result, nil
+}
+
+// finishPacket records the outcome of a transfer, the cooldown only applies if the tokens were received
+// or may have been
+func (s *Server) finishPacket(entry *PacketEntry, status string) {
+	response := entry.Response
+	s.log.Info("ibc transfer finished", "request_id", response.ID, "tx_hash", response.TxHash, "status", status, "sequences", entry.Sequences)
+	response.IBCStatus = status
+	s.saveResponse(response)
+	s.setHistoryIBCStatus(response.ID, status)
+	if status == client.PacketAcknowledged || status == client.PacketUnknown {
+		s.commitCooldown(response.ID)
+	} else {
+		s.releaseCooldown(response.ID)
+	}
+	err := s.store.Delete([]byte(packetKey(response.ID)))
+	if err != nil {
+		s.log.Error("error deleting packet entry", "error", err, "request_id", response.ID)
+	}
+	if response.Source == SourceAPI || s.discord == nil {
+		return
+	}
+	_, err = s.discord.ChannelMessageSend(response.ChannelID, packetMessage(response))
+	if err != nil {
+		s.log.Error("error sending message", "error", err)
+	}
+}
+
+func packetMessage(response *SendResponse) string {
+	switch response.IBCStatus {
+	case client.PacketAcknowledged:
+		return fmt.Sprintf("<@%s> your tokens were received by the counterparty chain", response.UserID)
+	case client.PacketFailed:
+		return fmt.Sprintf("<@%s> your transfer failed on the counterparty chain, you can send a request again", response.UserID)
+	case client.PacketUnknown:
+		return fmt.Sprintf("<@%s> your transfer was relayed but its outcome couldn't be confirmed, check your balance on the counterparty chain", response.UserID)
+	default:
+		return fmt.Sprintf("<@%s> your transfer timed out before it was received, you can send a request again", response.UserID)
+	}
+}
@@ -0,0 +1,192 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/public-awesome/faucet/config"
+)
+
+var faucetCommand = &discordgo.ApplicationCommand{
+	Name:        "faucet",
+	Description: "Request tokens from the faucet",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "request",
+			Description: "Request tokens to an address",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "address",
+					Description: "The address that receives the tokens",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "status",
+			Description: "Show the faucet status for this channel",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "cooldown",
+			Description: "Show when you can request tokens again",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "address",
+					Description: "The address that receives the tokens",
+				},
+			},
+		},
+	},
+}
+
+// registerCommands registers the faucet application command, replacing any previous version
+func (s *Server) registerCommands(ds *discordgo.Session) error {
+	_, err := ds.ApplicationCommandBulkOverwrite(ds.State.User.ID, "", []*discordgo.ApplicationCommand{faucetCommand})
+	return err
+}
+
+func (s *Server) popInteraction(id string) *discordgo.Interaction {
+	s.interactionsMu.Lock()
+	defer s.interactionsMu.Unlock()
+	interaction, ok := s.interactions[id]
+	if !ok {
+		return nil
+	}
+	delete(s.interactions, id)
+	return interaction
+}
+
+func (s *Server) respondEphemeral(ds *discordgo.Session, interaction *discordgo.Interaction, content string) {
+	err := ds.InteractionRespond(interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		s.log.Error("error responding to interaction", "error", err)
+	}
+}
+
+func interactionUser(interaction *discordgo.Interaction) *discordgo.User {
+	if interaction.Member != nil {
+		return interaction.Member.User
+	}
+	return interaction.User
+}
+
+func (s *Server) interactionHandler(ds *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionApplicationCommand {
+		return
+	}
+	data := i.ApplicationCommandData()
+	if data.Name != faucetCommand.Name || len(data.Options) == 0 {
+		return
+	}
+	user := interactionUser(i.Interaction)
+	if user == nil {
+		return
+	}
+
+	channel, err := ds.Channel(i.ChannelID)
+	if err != nil {
+		s.log.Error("error fetching channel details", "error", err, "channel", i.ChannelID)
+		s.respondEphemeral(ds, i.Interaction, "something went wrong, please try again later")
+		return
+	}
+	profile, ok := s.channelProfile(channel)
+	if !ok {
+		s.respondEphemeral(ds, i.Interaction, "the faucet is not available in this channel")
+		return
+	}
+
+	subcommand := data.Options[0]
+	options := make(map[string]string)
+	for _, option := range subcommand.Options {
+		options[option.Name] = strings.TrimSpace(option.StringValue())
+	}
+
+	switch subcommand.Name {
+	case "request":
+		req, reply := s.newDiscordRequest(profile, channel, user, options["address"])
+		if req == nil {
+			s.respondEphemeral(ds, i.Interaction, reply)
+			return
+		}
+		s.interactionsMu.Lock()
+		s.interactions[req.ID] = i.Interaction
+		s.interactionsMu.Unlock()
+
+		err := s.enqueue(req)
+		if err != nil {
+			s.log.Error("error queueing request", "error", err, "request_id", req.ID)
+			s.popInteraction(req.ID)
+			s.releaseCooldown(req.ID)
+			s.respondEphemeral(ds, i.Interaction, fmt.Sprintf("<@%s> your request has failed, please try again later", user.ID))
+			return
+		}
+		// the reply is only visible to the user, the result of the request replaces it
+		s.respondEphemeral(ds, i.Interaction, fmt.Sprintf("<@%s> your request has been sent, the transaction will be broadcasted in a few seconds", user.ID))
+	case "status":
+		// fetching the balances can take longer than the interaction deadline so the response is deferred
+		err := ds.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+		})
+		if err != nil {
+			s.log.Error("error responding to interaction", "error", err)
+			return
+		}
+		status := s.statusMessage(profile)
+		_, err = ds.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &status})
+		if err != nil {
+			s.log.Error("error editing interaction response", "error", err)
+		}
+	case "cooldown":
+		address := options["address"]
+		id := fmt.Sprintf("%s-%s", channel.GuildID, channel.ID)
+		block, waitTime := s.cooldown(id, address, user.ID, s.channelInterval(profile.Key()))
+		if !block {
+			s.respondEphemeral(ds, i.Interaction, "you can request tokens now")
+			return
+		}
+		s.respondEphemeral(ds, i.Interaction, fmt.Sprintf("you can send a request again <t:%d:R>", time.Now().Add(waitTime).UTC().Unix()))
+	}
+}
+
+func (s *Server) statusMessage(profile config.ChannelProfile) string {
+	channel, amount := profile.Key(), profile.Coins
+	var b strings.Builder
+	if s.isPaused(channel) {
+		b.WriteString("The faucet is empty in this channel, admins have been notified.\n")
+	} else {
+		b.WriteString("The faucet is active in this channel.\n")
+	}
+	fmt.Fprintf(&b, "Amount: `%s`, interval: `%s`\n", amount, s.channelInterval(channel))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c, err := s.chainClient(profile.Chain)
+	if err != nil {
+		s.log.Error("error getting chain client", "error", err)
+		return b.String()
+	}
+	balances, err := c.WalletBalances(ctx, cw20Contracts(profile.Coins)...)
+	if err != nil {
+		s.log.Error("error fetching wallet balances", "error", err)
+		return b.String()
+	}
+	if granter := c.AuthzGranter(); granter != "" {
+		fmt.Fprintf(&b, "Coins are sent on behalf of `%s`\n", granter)
+	}
+	fmt.Fprintf(&b, "Wallets: %s", formatBalances(balances))
+	return b.String()
+}
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/public-awesome/faucet/client"
+)
+
+// requestQueueSize is the number of requests buffered between the frontends and the processor
+const requestQueueSize = 1000
+
+const (
+	StatusQueued       = "queued"
+	StatusBroadcasting = "broadcasting"
+)
+
+// QueueEntry is the durable state of a request, it is written before the request is acknowledged
+// so unfinished requests can be replayed after a restart, and deleted once the response is saved
+type QueueEntry struct {
+	Request   *SendRequest `json:"request"`
+	Status    string       `json:"status"`
+	UpdatedAt time.Time    `json:"updated_at"`
+	// TxHashes are the transactions signed for the request, each is recorded before it's broadcasted
+	TxHashes []string `json:"tx_hashes,omitempty"`
+}
+
+const queuePrefix = "queue-"
+
+func queueKey(id string) string {
+	return fmt.Sprintf("%s%s", queuePrefix, id)
+}
+
+func (s *Server) getQueueEntry(id string) (*QueueEntry, error) {
+	b, err := s.store.Get([]byte(queueKey(id)))
+	if err != nil {
+		return nil, err
+	}
+	var entry QueueEntry
+	err = json.Unmarshal(b, &entry)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (s *Server) setQueueStatus(req *SendRequest, status string, txHashes ...string) error {
+	b, err := json.Marshal(QueueEntry{Request: req, Status: status, UpdatedAt: time.Now(), TxHashes: txHashes})
+	if err != nil {
+		return err
+	}
+	return s.store.Set([]byte(queueKey(req.ID)), b)
+}
+
+// withTxHashes returns a context that records the hash of every transaction sending the requests in
+// their queue entries before it's broadcasted, so a replay can look it up instead of sending it again
+func (s *Server) withTxHashes(ctx context.Context, reqs ...*SendRequest) context.Context {
+	return client.WithBroadcastHook(ctx, func(txHash string) error {
+		batch := s.store.Batch()
+		defer batch.Close()
+		for _, req := range reqs {
+			entry, err := s.getQueueEntry(req.ID)
+			if err != nil {
+				return err
+			}
+			entry.TxHashes = append(entry.TxHashes, txHash)
+			entry.UpdatedAt = time.Now()
+			b, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+			err = batch.Set([]byte(queueKey(req.ID)), b)
+			if err != nil {
+				return err
+			}
+		}
+		return batch.Commit()
+	})
+}
+
+// enqueue persists the request and hands it to the processor, it must be called before the request is acknowledged
+func (s *Server) enqueue(req *SendRequest) error {
+	err := s.setQueueStatus(req, StatusQueued)
+	if err != nil {
+		return err
+	}
+	s.requests <- req
+	return nil
+}
+
+// startProcessing marks the request as broadcasting, it returns false if the request was already processed.
+// A request that was broadcasting before a restart is only sent again if none of its transactions was included
+func (s *Server) startProcessing(ctx context.Context, req *SendRequest) bool {
+	entry, err := s.getQueueEntry(req.ID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		s.log.Error("error getting queue entry", "error", err, "request_id", req.ID)
+	}
+	if _, err := s.getResponse(req.ID); err == nil {
+		s.log.Info("skipping processed request", "request_id", req.ID)
+		return false
+	}
+	var txHashes []string
+	if entry != nil && len(entry.TxHashes) > 0 {
+		result := s.includedTx(ctx, entry)
+		if result != nil {
+			s.log.Info("replayed request was already sent", "request_id", req.ID, "tx_hash", result.TxHash)
+			response := newSendResponse(req, result, nil)
+			if response.IBCStatus == client.PacketPending {
+				s.trackPacket(ctx, req, response)
+			}
+			s.responses <- response
+			return false
+		}
+		s.log.Warn("transactions of replayed request were not included, sending it again", "request_id", req.ID, "tx_hashes", entry.TxHashes)
+		txHashes = entry.TxHashes
+	}
+	err = s.setQueueStatus(req, StatusBroadcasting, txHashes...)
+	if err != nil {
+		s.log.Error("error updating queue entry", "error", err, "request_id", req.ID)
+	}
+	return true
+}
+
+// replayTxTimeout is how long after its last broadcast the transactions of a replayed request are looked
+// up, a transaction that is still not included after that is not expected to be
+const replayTxTimeout = time.Minute
+
+// replayTxInterval is the interval between lookups of the transactions of a replayed request
+const replayTxInterval = 2 * time.Second
+
+// includedTx returns the result of the transaction of the entry that was included in a block or nil if
+// none was included before the replay timeout
+func (s *Server) includedTx(ctx context.Context, entry *QueueEntry) *client.TxResult {
+	c, err := s.chainClient(entry.Request.Chain)
+	if err != nil {
+		return nil
+	}
+	timeout := time.NewTimer(time.Until(entry.UpdatedAt.Add(replayTxTimeout)))
+	defer timeout.Stop()
+	ticker := time.NewTicker(replayTxInterval)
+	defer ticker.Stop()
+	for {
+		failed := 0
+		for _, txHash := range entry.TxHashes {
+			result, err := c.TxStatus(ctx, txHash)
+			switch {
+			case err == nil:
+				return result
+			case errors.Is(err, client.ErrTxFailed):
+				failed++
+			case !errors.Is(err, client.ErrTxNotFound):
+				s.log.Error("error looking up transaction", "error", err, "request_id", entry.Request.ID, "tx_hash", txHash)
+			}
+		}
+		if failed == len(entry.TxHashes) {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-timeout.C:
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// replayQueue requeues the requests that were not finished before the last shutdown, requests that
+// were broadcasting are only sent again if their transactions were not included, see startProcessing
+func (s *Server) replayQueue() error {
+	var unfinished []*SendRequest
+	err := s.store.Iterate([]byte(queuePrefix), func(key, value []byte) error {
+		var entry QueueEntry
+		err := json.Unmarshal(value, &entry)
+		if err != nil {
+			s.log.Error("error decoding queue entry", "error", err, "key", string(key))
+			return nil
+		}
+		unfinished = append(unfinished, entry.Request)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(unfinished) == 0 {
+		return nil
+	}
+	s.log.Info("replaying unfinished requests", "count", len(unfinished))
+	go func() {
+		for _, req := range unfinished {
+			s.requests <- req
+		}
+	}()
+	return nil
+}
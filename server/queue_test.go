@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/public-awesome/faucet/client"
+	"github.com/public-awesome/faucet/config"
+)
+
+func TestReplayBroadcasting(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		// code is the code of the recorded transaction once included, nil if it was never included
+		code       *uint32
+		broadcasts int
+	}{
+		{name: "included tx is not sent again", code: new(uint32), broadcasts: 0},
+		{name: "failed tx is sent again", code: func() *uint32 { code := uint32(5); return &code }(), broadcasts: 1},
+		{name: "missing tx is sent again after the timeout", broadcasts: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			chain := newFakeChain(t, func(int) (uint32, bool) { return 0, true })
+			if tc.code != nil {
+				chain.include("AB01", *tc.code)
+			}
+			s := testServer(&config.Config{})
+			s.clients = map[string]*client.Client{"": chain.client(t)}
+			s.responses = make(chan *SendResponse, 1)
+
+			req := batchRequests(t, 1)[0]
+			entry := QueueEntry{Request: req, Status: StatusBroadcasting, UpdatedAt: time.Now().Add(-replayTxTimeout), TxHashes: []string{"AB01"}}
+			b, err := json.Marshal(entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = s.store.Set([]byte(queueKey(req.ID)), b)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			s.processRequest(context.Background(), req)
+			if broadcasts := chain.broadcastCount(); broadcasts != tc.broadcasts {
+				t.Fatalf("expected %d broadcasts, got %d", tc.broadcasts, broadcasts)
+			}
+			response := <-s.responses
+			if !response.Success {
+				t.Fatalf("unexpected response %+v", response)
+			}
+			if tc.broadcasts == 0 && (response.TxHash != "AB01" || !response.Confirmed) {
+				t.Fatalf("expected the included tx, got %+v", response)
+			}
+			stored, err := s.getQueueEntry(req.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the hash of the new transaction is recorded next to the old one before it's broadcasted
+			if len(stored.TxHashes) != 1+tc.broadcasts || stored.TxHashes[len(stored.TxHashes)-1] != response.TxHash {
+				t.Fatalf("unexpected tx hashes %v for %s", stored.TxHashes, response.TxHash)
+			}
+		})
+	}
+}
+
+func TestQueueCleanup(t *testing.T) {
+	s := testServer(&config.Config{})
+	s.requests = make(chan *SendRequest, 10)
+	for _, entry := range []QueueEntry{
+		{Request: &SendRequest{ID: "queued"}, Status: StatusQueued},
+		{Request: &SendRequest{ID: "done"}, Status: StatusBroadcasting},
+	} {
+		err := s.setQueueStatus(entry.Request, entry.Status)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s.saveResponse(&SendResponse{ID: "done", Success: true})
+	if _, err := s.getQueueEntry("done"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected the queue entry to be deleted with the response, got %v", err)
+	}
+	if s.startProcessing(context.Background(), &SendRequest{ID: "done"}) {
+		t.Fatal("expected a request with a response to be skipped")
+	}
+
+	err := s.replayQueue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req := <-s.requests; req.ID != "queued" {
+		t.Fatalf("expected the queued request to be replayed, got %s", req.ID)
+	}
+	select {
+	case req := <-s.requests:
+		t.Fatalf("expected only the unfinished request to be replayed, got %s", req.ID)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/public-awesome/faucet/config"
+)
+
+// reloadDebounce groups the events of an editor saving the config file into a single reload
+const reloadDebounce = time.Second
+
+// keepSetting restores a setting that is only read on startup and records its name if it changed
+func keepSetting[T comparable](changed *[]string, name string, current T, next *T) {
+	if current != *next {
+		*changed = append(*changed, name)
+		*next = current
+	}
+}
+
+// keepRestartSettings copies the settings that are only read on startup from the running config,
+// it returns the names of the ones that changed
+func keepRestartSettings(current, next *config.Config) []string {
+	var changed []string
+	keepSetting(&changed, "FAUCET_MNEMONICS", current.FaucetMnemonics, &next.FaucetMnemonics)
+	keepSetting(&changed, "FAUCET_BOT_TOKEN", current.FaucetBotToken, &next.FaucetBotToken)
+	keepSetting(&changed, "FAUCET_CLIENT_*", current.ClientConfig, &next.ClientConfig)
+	// a client is created per chain on startup so the chains can't be added or changed either
+	if !reflect.DeepEqual(current.Chains, next.Chains) {
+		changed = append(changed, "chains")
+		next.Chains = current.Chains
+	}
+	keepSetting(&changed, "FAUCET_STORE_PATH", current.StorePath, &next.StorePath)
+	keepSetting(&changed, "FAUCET_STORE_BACKEND", current.StoreBackend, &next.StoreBackend)
+	keepSetting(&changed, "FAUCET_LEGACY_COMMANDS", current.LegacyCommands, &next.LegacyCommands)
+	keepSetting(&changed, "FAUCET_TREASURY_MNEMONICS", current.TreasuryMnemonics, &next.TreasuryMnemonics)
+	if (current.TreasuryIndex == nil) != (next.TreasuryIndex == nil) ||
+		(current.TreasuryIndex != nil && *current.TreasuryIndex != *next.TreasuryIndex) {
+		changed = append(changed, "FAUCET_TREASURY_INDEX")
+	}
+	next.TreasuryIndex = current.TreasuryIndex
+	keepSetting(&changed, "FAUCET_TOPUP_INTERVAL", current.TopUpInterval, &next.TopUpInterval)
+	// the top-up loop parses the threshold and amount once when it starts
+	keepSetting(&changed, "FAUCET_TOPUP_THRESHOLD", current.TopUpThreshold, &next.TopUpThreshold)
+	keepSetting(&changed, "FAUCET_TOPUP_AMOUNT", current.TopUpAmount, &next.TopUpAmount)
+	// the tokens decide on startup whether the api server runs at all
+	keepSetting(&changed, "FAUCET_API_TOKEN", current.APIToken, &next.APIToken)
+	keepSetting(&changed, "FAUCET_ADMIN_TOKEN", current.AdminToken, &next.AdminToken)
+	keepSetting(&changed, "FAUCET_BALANCE_CHECK_INTERVAL", current.BalanceCheckInterval, &next.BalanceCheckInterval)
+	keepSetting(&changed, "FAUCET_COOLDOWN_SWEEP_INTERVAL", current.CooldownSweepInterval, &next.CooldownSweepInterval)
+	keepSetting(&changed, "FAUCET_CONFIG_FILE", current.ConfigFile, &next.ConfigFile)
+	keepSetting(&changed, "PORT", current.Port, &next.Port)
+	return changed
+}
+
+// reloadConfig reads and validates the config, the new config is used by new requests if it's
+// valid and the current one is kept otherwise
+func (s *Server) reloadConfig() error {
+	next, err := config.NewConfig()
+	if err != nil {
+		return err
+	}
+	// the restart settings are restored first so channels can't switch to a chain without a client
+	changed := keepRestartSettings(s.config(), next)
+	err = next.Validate()
+	if err != nil {
+		return err
+	}
+	for _, setting := range changed {
+		s.log.Warn("config setting changed, it requires a restart to take effect", "setting", setting)
+	}
+	s.currentConfig.Store(next)
+	s.log.Info("reloaded config", "channels", len(next.Channels))
+	return nil
+}
+
+// watchConfig reloads the config on SIGHUP and when the config file changes
+func (s *Server) watchConfig(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var (
+		events <-chan fsnotify.Event
+		errs   <-chan error
+	)
+	path := s.config().ConfigFile
+	if path != "" {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			s.log.Error("error creating config watcher", "error", err)
+		} else {
+			defer watcher.Close()
+			// the directory is watched since editors often replace the file instead of writing it
+			err = watcher.Add(filepath.Dir(path))
+			if err != nil {
+				s.log.Error("error watching config file", "error", err, "path", path)
+			}
+			events, errs = watcher.Events, watcher.Errors
+		}
+	}
+
+	var debounce <-chan time.Time
+	reload := func(trigger string) {
+		s.log.Info("reloading config", "trigger", trigger)
+		err := s.reloadConfig()
+		if err != nil {
+			s.log.Error("error reloading config, keeping the current config", "error", err)
+		}
+	}
+	for {
+		select {
+		case <-hup:
+			reload("signal")
+		case event := <-events:
+			if filepath.Clean(event.Name) == filepath.Clean(path) && event.Has(fsnotify.Write|fsnotify.Create) {
+				debounce = time.After(reloadDebounce)
+			}
+		case <-debounce:
+			debounce = nil
+			reload("file")
+		case err := <-errs:
+			s.log.Error("error watching config file", "error", err)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
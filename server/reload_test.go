@@ -0,0 +1,60 @@
+package server
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/public-awesome/faucet/config"
+)
+
+func TestReloadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "faucet.yaml")
+	t.Setenv("FAUCET_CONFIG_FILE", path)
+	t.Setenv("FAUCET_BOT_TOKEN", "token")
+	t.Setenv("FAUCET_CLIENT_RPC_ENDPOINT", "http://localhost:26657")
+	t.Setenv("FAUCET_CLIENT_API_ENDPOINT", "http://localhost:1317")
+	t.Setenv("FAUCET_CLIENT_ACCOUNT_PREFIX", "stars")
+	t.Setenv("FAUCET_CLIENT_GAS_PRICES", "1ustars")
+	t.Setenv("FAUCET_CLIENT_CHAIN_ID", "elgafar-1")
+
+	write := func(content string) {
+		err := os.WriteFile(path, []byte(content), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("channels:\n  - name: faucet\n    coins: 1ustars\n")
+	current, err := config.NewConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{log: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	s.currentConfig.Store(current)
+
+	write("channels:\n  - name: faucet\n    coins: 2ustars\n")
+	t.Setenv("FAUCET_API_TOKEN", "changed")
+	t.Setenv("FAUCET_TOPUP_THRESHOLD", "1ustars")
+	err = s.reloadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if coins := s.config().FaucetChannelCoins["faucet"].Coins; coins != "2ustars" {
+		t.Fatalf("expected the reloaded amount, got %s", coins)
+	}
+	// settings read on startup keep their running value until a restart
+	if s.config().APIToken != current.APIToken || s.config().TopUpThreshold != current.TopUpThreshold {
+		t.Fatalf("expected the startup settings to be kept, got %+v", s.config())
+	}
+
+	write("channels:\n  - name: faucet\n    coins: ustars2\n")
+	err = s.reloadConfig()
+	if err == nil {
+		t.Fatal("expected an invalid config error")
+	}
+	if coins := s.config().FaucetChannelCoins["faucet"].Coins; coins != "2ustars" {
+		t.Fatalf("expected the previous config to be kept, got %s", coins)
+	}
+}
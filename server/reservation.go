@@ -0,0 +1,112 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CooldownReservation records the cooldown keys written for a request and their previous values
+// so they can be restored if the request fails
+type CooldownReservation struct {
+	Keys      []ReservedKey `json:"keys"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+type ReservedKey struct {
+	Key string `json:"key"`
+	// Previous is the value before the reservation, nil if the key didn't exist
+	Previous []byte `json:"previous"`
+}
+
+func reservationKey(id string) string {
+	return fmt.Sprintf("reservation-%s", id)
+}
+
+// reserveCooldown sets the keys to the current time with an expiry after the period and records their
+// previous values, must be called with the cooldown lock held
+func (s *Server) reserveCooldown(requestID string, period time.Duration, keys ...string) error {
+	now := time.Now()
+	entry, err := json.Marshal(CooldownEntry{LastRequest: now, ExpiresAt: now.Add(period)})
+	if err != nil {
+		return err
+	}
+	reservation := CooldownReservation{CreatedAt: now}
+	for _, key := range keys {
+		previous, err := s.store.Get([]byte(key))
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		reservation.Keys = append(reservation.Keys, ReservedKey{Key: key, Previous: previous})
+	}
+	b, err := json.Marshal(reservation)
+	if err != nil {
+		return err
+	}
+	batch := s.store.Batch()
+	defer batch.Close()
+	err = batch.Set([]byte(reservationKey(requestID)), b)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		err = setWithTTL(batch, []byte(key), entry, period)
+		if err != nil {
+			return err
+		}
+	}
+	return batch.Commit()
+}
+
+// commitCooldown keeps the cooldown of a successful request
+func (s *Server) commitCooldown(requestID string) {
+	err := s.store.Delete([]byte(reservationKey(requestID)))
+	if err != nil {
+		s.log.Error("error committing cooldown", "error", err, "request_id", requestID)
+	}
+}
+
+// releaseCooldown restores the cooldown keys of a failed request so the user can retry right away
+func (s *Server) releaseCooldown(requestID string) {
+	s.cooldownMu.Lock()
+	defer s.cooldownMu.Unlock()
+	b, err := s.store.Get([]byte(reservationKey(requestID)))
+	if errors.Is(err, ErrNotFound) {
+		return
+	}
+	if err != nil {
+		s.log.Error("error getting cooldown reservation", "error", err, "request_id", requestID)
+		return
+	}
+	var reservation CooldownReservation
+	err = json.Unmarshal(b, &reservation)
+	if err != nil {
+		s.log.Error("error decoding cooldown reservation", "error", err, "request_id", requestID)
+		return
+	}
+	batch := s.store.Batch()
+	defer batch.Close()
+	for _, key := range reservation.Keys {
+		if key.Previous == nil {
+			err = batch.Delete([]byte(key.Key))
+		} else {
+			err = batch.Set([]byte(key.Key), key.Previous)
+		}
+		if err != nil {
+			s.log.Error("error releasing cooldown", "error", err, "request_id", requestID, "key", key.Key)
+			return
+		}
+	}
+	err = batch.Delete([]byte(reservationKey(requestID)))
+	if err != nil {
+		s.log.Error("error deleting cooldown reservation", "error", err, "request_id", requestID)
+		return
+	}
+	err = batch.Commit()
+	if err != nil {
+		s.log.Error("error releasing cooldown", "error", err, "request_id", requestID)
+		return
+	}
+	s.log.Info("released cooldown of failed request", "request_id", requestID)
+}
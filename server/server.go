@@ -2,9 +2,12 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
-	"path"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
@@ -12,8 +15,14 @@ import (
 	"github.com/public-awesome/faucet/config"
 )
 
+const (
+	SourceDiscord = "discord"
+	SourceAPI     = "api"
+)
+
 type SendRequest struct {
 	ID          string `json:"id"`
+	Source      string `json:"source"`
 	GuildID     string `json:"guild_id"`
 	ChannelID   string `json:"channel_id"`
 	ChannelName string `json:"channel_name"`
@@ -21,18 +30,45 @@ type SendRequest struct {
 	UserID      string `json:"user_id"`
 	Amount      string `json:"amount"`
 	Address     string `json:"address"`
+	// Channel is the key of the channel profile
+	Channel string `json:"channel"`
+	// Chain is the name of the chain the request is sent on, empty for the default chain
+	Chain string `json:"chain"`
+	// IBC is the transfer channel of requests sent to a counterparty chain
+	IBC *config.ChannelIBC `json:"ibc,omitempty"`
+	// FeeGrant is the allowance granted instead of sending the amount
+	FeeGrant *config.ChannelFeeGrant `json:"fee_grant,omitempty"`
 }
 
 type SendResponse struct {
 	ID          string `json:"id"`
+	Source      string `json:"source"`
 	GuildID     string `json:"guild_id"`
 	ChannelID   string `json:"channel_id"`
 	ChannelName string `json:"channel_name"`
 	User        string `json:"user"`
 	UserID      string `json:"user_id"`
+	Address     string `json:"address"`
+	Amount      string `json:"amount"`
 	TxHash      string `json:"tx_hash"`
 	Success     bool   `json:"success"`
 	Error       string `json:"error"`
+	Confirmed   bool   `json:"confirmed"`
+	Height      int64  `json:"height"`
+	Code        uint32 `json:"code"`
+	GasUsed     int64  `json:"gas_used"`
+	RawLog      string `json:"raw_log"`
+	// Channel is the key of the channel profile
+	Channel string `json:"channel"`
+	// Chain is the name of the chain the request is sent on, empty for the default chain
+	Chain string `json:"chain"`
+	// IBCStatus is the status of the packets of IBC transfers, see the client Packet constants
+	IBCStatus string `json:"ibc_status,omitempty"`
+	// FeeGranter is the faucet wallet that granted the fee allowance, grantees set it as the fee granter
+	FeeGranter string `json:"fee_granter,omitempty"`
+	// Pending is set when the transaction was broadcasted but its outcome is unknown, for example after
+	// a confirmation timeout. The cooldown is kept since the transaction may still be included
+	Pending bool `json:"pending,omitempty"`
 }
 
 type Server struct {
@@ -40,9 +76,28 @@ type Server struct {
 	responses chan *SendResponse
 	client    *client.Client
 	log       *slog.Logger
-	config    *config.Config
+	// clients holds the client of every chain by name, client is the one of the default chain
+	clients map[string]*client.Client
+	// currentConfig is swapped when the config is reloaded, use config() to read it
+	currentConfig atomic.Pointer[config.Config]
+
+	store Store
+	// cooldownMu makes checking and reserving a cooldown atomic
+	cooldownMu sync.Mutex
+
+	// pending holds the API requests waiting for a response
+	pendingMu sync.Mutex
+	pending   map[string]chan *SendResponse
+
+	// paused holds the channels whose amount can't be covered by the wallets
+	pausedMu sync.Mutex
+	paused   map[string]bool
 
-	store *Store
+	discord *discordgo.Session
+
+	// interactions holds the slash command interactions whose response is edited with the result
+	interactionsMu sync.Mutex
+	interactions   map[string]*discordgo.Interaction
 }
 
 func NewServer(log *slog.Logger) (*Server, error) {
@@ -51,68 +106,253 @@ func NewServer(log *slog.Logger) (*Server, error) {
 
 		return nil, err
 	}
-	client := client.New(
-		client.WithRPC(config.ClientConfig.RPCEndpoint),
-		client.WithAPI(config.ClientConfig.APIEndpoint),
-		client.WithAccountPrefix(config.ClientConfig.AccountPrefix),
-		client.WithFaucetMnemonics(config.FaucetMnemonics),
-		client.WithCoinType(config.ClientConfig.CoinType),
-		client.WithChainID(config.ClientConfig.ChainID),
-		client.WithGasAmount(config.ClientConfig.GasAmount),
-		client.WithGasPrices(config.ClientConfig.GasPrices),
-	)
-
-	store, err := NewStore(path.Join(config.StorePath, "faucet.db"))
+	// the chain checks are added to the same report so every problem is found in one startup
+	err = config.Validate()
+	clients := make(map[string]*client.Client)
+	chains := []string{""}
+	for _, chain := range config.Chains {
+		chains = append(chains, chain.Name)
+	}
+	for _, name := range chains {
+		chain, _ := config.Chain(name)
+		opts := []client.ClientOption{
+			client.WithRPC(chain.ClientConfig.RPCEndpoint),
+			client.WithAPI(chain.ClientConfig.APIEndpoint),
+			client.WithAccountPrefix(chain.ClientConfig.AccountPrefix),
+			client.WithFaucetMnemonics(chain.Mnemonics),
+			client.WithCoinType(chain.ClientConfig.CoinType),
+			client.WithChainID(chain.ClientConfig.ChainID),
+			client.WithGasAmount(chain.ClientConfig.GasAmount),
+			client.WithGasPrices(chain.ClientConfig.GasPrices),
+			client.WithGasAdjustment(chain.ClientConfig.GasAdjustment),
+			client.WithSimulateGas(chain.ClientConfig.SimulateGas),
+			client.WithFeeMode(chain.ClientConfig.FeeMode, chain.ClientConfig.FeeCacheTTL, chain.ClientConfig.MaxGasPrice),
+			client.WithConfirmTimeout(chain.ClientConfig.ConfirmTimeout),
+			client.WithAccounts(chain.ClientConfig.Accounts),
+			client.WithKeyAlgo(chain.ClientConfig.KeyAlgo),
+			client.WithAuthzGranter(chain.ClientConfig.AuthzGranter),
+		}
+		// the treasury only tops up the wallets of the default chain
+		if name == "" {
+			opts = append(opts,
+				client.WithTreasuryMnemonics(config.TreasuryMnemonics),
+				client.WithTreasuryIndex(config.TreasuryIndex),
+			)
+		}
+		c, clientErr := client.New(opts...)
+		if clientErr != nil {
+			if name != "" {
+				clientErr = fmt.Errorf("chain %s: %w", name, clientErr)
+			}
+			err = errors.Join(err, clientErr)
+			continue
+		}
+		clients[name] = c
+
+		if config.VerifyChain {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			verifyErr := clients[name].Verify(ctx, config.Denoms(name))
+			cancel()
+			if verifyErr != nil && name != "" {
+				verifyErr = fmt.Errorf("chain %s: %w", name, verifyErr)
+			}
+			err = errors.Join(err, verifyErr)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid config:\n%w", err)
+	}
+
+	store, err := NewStore(config.StoreBackend, config.StorePath)
 	if err != nil {
 		return nil, err
 	}
-	return &Server{
-		requests:  make(chan *SendRequest),
+	s := &Server{
+		requests:  make(chan *SendRequest, requestQueueSize),
 		responses: make(chan *SendResponse),
-		client:    client,
+		client:    clients[""],
+		clients:   clients,
 		log:       log,
-		config:    config,
 		store:     store,
-	}, nil
+		pending:   make(map[string]chan *SendResponse),
+		paused:    make(map[string]bool),
+
+		interactions: make(map[string]*discordgo.Interaction),
+	}
+	s.currentConfig.Store(config)
+	return s, nil
 }
 
-func (s *Server) ProcessRequests(ctx context.Context) {
+// chains returns the names of the chains in order, the default chain is first
+func (s *Server) chains() []string {
+	chains := make([]string, 0, len(s.clients))
+	for chain := range s.clients {
+		chains = append(chains, chain)
+	}
+	sort.Strings(chains)
+	return chains
+}
 
+// chainClient returns the client of the chain, the empty name is the default chain
+func (s *Server) chainClient(chain string) (*client.Client, error) {
+	c, ok := s.clients[chain]
+	if !ok {
+		return nil, fmt.Errorf("chain %q is not configured", chain)
+	}
+	return c, nil
+}
+
+// config returns the current config, read it once per request so a reload doesn't mix two configs
+func (s *Server) config() *config.Config {
+	return s.currentConfig.Load()
+}
+
+// ProcessRequests dispatches the requests to the workers of their chain. Each chain runs one send per
+// wallet in parallel so the requests of a busy chain can't take the workers of the others
+func (s *Server) ProcessRequests(ctx context.Context) {
+	queues := make(map[string]chan *SendRequest, len(s.clients))
+	for chain, c := range s.clients {
+		queue := make(chan *SendRequest, requestQueueSize)
+		queues[chain] = queue
+		for i := 0; i < c.Wallets(); i++ {
+			go s.processQueue(ctx, queue)
+		}
+	}
 	for {
 		select {
 		case req := <-s.requests:
-			s.log.Info("processing request", "request_id", req.ID, "channel", req.ChannelName, "user", req.User, "user_id", req.UserID, "amount", req.Amount, "address", req.Address)
-
-			txHash, err := s.client.BankSend(ctx, req.Address, req.Amount)
-			success := true
-			var errMsg string
-
-			if err != nil {
-				success = false
-				errMsg = err.Error()
-				s.log.Error("error sending request", "error", err)
+			queue, ok := queues[req.Chain]
+			if !ok {
+				// the request is answered with the unknown chain error right away
+				go s.processRequest(ctx, req)
+				continue
 			}
-
-			s.responses <- &SendResponse{
-				ID:          req.ID,
-				GuildID:     req.GuildID,
-				ChannelID:   req.ChannelID,
-				ChannelName: req.ChannelName,
-				User:        req.User,
-				UserID:      req.UserID,
-				TxHash:      txHash,
-				Success:     success,
-				Error:       errMsg,
+			select {
+			case queue <- req:
+			case <-ctx.Done():
+				s.log.Info("stopping request processor")
+				return
 			}
-			<-time.After(5 * time.Second)
 		case <-ctx.Done():
 			s.log.Info("stopping request processor")
 			return
 		}
 	}
 }
+
+// processQueue sends the requests of a chain one at a time, or in batches when a batch window is set
+func (s *Server) processQueue(ctx context.Context, queue <-chan *SendRequest) {
+	for {
+		select {
+		case req := <-queue:
+			if s.config().BatchWindow > 0 {
+				s.processBatch(ctx, s.collectBatch(ctx, queue, req))
+				continue
+			}
+			s.processRequest(ctx, req)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Server) processRequest(ctx context.Context, req *SendRequest) {
+	if !s.startProcessing(ctx, req) {
+		return
+	}
+	ctx = s.withTxHashes(ctx, req)
+	s.log.Info("processing request", "request_id", req.ID, "channel", req.ChannelName, "user", req.User, "user_id", req.UserID, "amount", req.Amount, "address", req.Address)
+	c, err := s.chainClient(req.Chain)
+	if err != nil {
+		s.responses <- newSendResponse(req, nil, err)
+		return
+	}
+	if !s.canSend(ctx, c, req) {
+		s.responses <- newSendResponse(req, nil, errors.New(faucetEmptyError))
+		return
+	}
+
+	if req.IBC != nil {
+		result, err := c.IBCTransfer(ctx, req.IBC.SourcePort, req.IBC.SourceChannel, req.Address, req.Amount, req.IBC.Timeout)
+		if err != nil {
+			s.log.Error("error sending ibc transfer", "error", err)
+		}
+		response := newSendResponse(req, result, err)
+		if response.IBCStatus == client.PacketPending {
+			s.trackPacket(ctx, req, response)
+		}
+		s.responses <- response
+		return
+	}
+
+	if req.FeeGrant != nil {
+		allowance := client.FeeAllowance{
+			SpendLimit:       req.Amount,
+			Expiration:       req.FeeGrant.Expiration,
+			Period:           req.FeeGrant.Period,
+			PeriodSpendLimit: req.FeeGrant.PeriodLimit,
+		}
+		result, granter, err := c.GrantFeeAllowance(ctx, req.Address, allowance)
+		if err != nil {
+			s.log.Error("error granting fee allowance", "error", err)
+		}
+		response := newSendResponse(req, result, err)
+		if err == nil {
+			response.FeeGranter = granter
+		}
+		s.responses <- response
+		return
+	}
+
+	result, err := c.BankSend(ctx, req.Address, req.Amount)
+	if err != nil {
+		s.log.Error("error sending request", "error", err)
+	}
+	s.responses <- newSendResponse(req, result, err)
+}
+
+func newSendResponse(req *SendRequest, result *client.TxResult, err error) *SendResponse {
+	success := true
+	var errMsg string
+	if err != nil {
+		success = false
+		errMsg = err.Error()
+	}
+	pending := err != nil && !client.TxFailed(result, err)
+	if result == nil {
+		result = &client.TxResult{}
+	}
+	var ibcStatus string
+	if req.IBC != nil && (success || pending) {
+		ibcStatus = client.PacketPending
+	}
+	return &SendResponse{
+		ID:          req.ID,
+		Source:      req.Source,
+		GuildID:     req.GuildID,
+		ChannelID:   req.ChannelID,
+		ChannelName: req.ChannelName,
+		Channel:     req.Channel,
+		Chain:       req.Chain,
+		User:        req.User,
+		UserID:      req.UserID,
+		Address:     req.Address,
+		Amount:      req.Amount,
+		TxHash:      result.TxHash,
+		Success:     success,
+		Error:       errMsg,
+		Confirmed:   result.Confirmed,
+		Height:      result.Height,
+		Code:        result.Code,
+		GasUsed:     result.GasUsed,
+		RawLog:      result.RawLog,
+		IBCStatus:   ibcStatus,
+		Pending:     pending,
+	}
+}
+
 func (s *Server) welcomeMessage(ds *discordgo.Session) {
-	if s.config.DisableWelcomeMessage {
+	if s.config().DisableWelcomeMessage {
 		return
 	}
 	for _, guild := range ds.State.Guilds {
@@ -122,24 +362,49 @@ func (s *Server) welcomeMessage(ds *discordgo.Session) {
 			continue
 		}
 		for _, channel := range channels {
-			for faucetChannel := range s.config.FaucetChannelCoins {
-				if faucetChannel == channel.Name {
-					m, err := ds.ChannelMessageSend(channel.ID, fmt.Sprintf("Welcome to the Stargaze Faucet! Please use the `$request %s1zxcvaqswdedefr...` command to request tokens.", s.config.ClientConfig.AccountPrefix))
-					if err != nil {
-						s.log.Error("error sending welcome message", "error", err, "channel", channel)
-						continue
-					}
-					if m != nil {
-						s.log.Info("sent welcome message", "channel", channel, "message", m.ID)
-					}
-				}
+			profile, ok := s.channelProfile(channel)
+			if !ok {
+				continue
+			}
+			command := "/faucet request address:"
+			if s.config().LegacyCommands {
+				command = "$request "
+			}
+			welcome := fmt.Sprintf("Welcome to the Stargaze Faucet! Please use the `%s%s1zxcvaqswdedefr...` command to request tokens.", command, s.addressPrefix(profile))
+			if profile.Messages.Welcome != "" {
+				welcome = profile.Messages.Welcome
+			}
+			m, err := ds.ChannelMessageSend(channel.ID, welcome)
+			if err != nil {
+				s.log.Error("error sending welcome message", "error", err, "channel", channel)
+				continue
+			}
+			if m != nil {
+				s.log.Info("sent welcome message", "channel", channel, "message", m.ID)
 			}
 		}
 	}
 }
+func (s *Server) logWallets(ctx context.Context) {
+	for _, chain := range s.chains() {
+		c := s.clients[chain]
+		for i, address := range c.Addresses() {
+			balances, err := c.Balances(ctx, address)
+			if err != nil {
+				s.log.Error("error fetching wallet balance", "error", err, "chain", chain, "index", i, "address", address)
+				continue
+			}
+			s.log.Info("using faucet address", "chain", chain, "index", i, "address", address, "balances", balances.String())
+		}
+	}
+	if treasury := s.client.TreasuryAddress(); treasury != "" {
+		s.log.Info("using treasury address", "address", treasury)
+	}
+}
+
 func (s *Server) Run(ctx context.Context) error {
 	s.log.Info("starting server")
-	s.log.Info("using faucet address", "address", s.client.FaucetAddress())
+	s.logWallets(ctx)
 
 	defer func() {
 		err := s.store.Close()
@@ -149,13 +414,19 @@ func (s *Server) Run(ctx context.Context) error {
 	}()
 
 	// Create a new Discord session using the provided bot token.
-	dg, err := discordgo.New("Bot " + s.config.FaucetBotToken)
+	dg, err := discordgo.New("Bot " + s.config().FaucetBotToken)
 	if err != nil {
 		s.log.Error("error creating discord session", "error", err)
 		return err
 	}
 
-	dg.AddHandler(s.messageHandler)
+	dg.Identify.Intents = discordgo.IntentsGuilds
+	dg.AddHandler(s.interactionHandler)
+	if s.config().LegacyCommands {
+		// the $request command requires the privileged message content intent
+		dg.Identify.Intents |= discordgo.IntentsGuildMessages | discordgo.IntentsMessageContent
+		dg.AddHandler(s.messageHandler)
+	}
 	// Open the websocket and begin listening.
 	err = dg.Open()
 	if err != nil {
@@ -163,9 +434,42 @@ func (s *Server) Run(ctx context.Context) error {
 		return err
 	}
 	defer dg.Close()
+	s.discord = dg
+	err = s.registerCommands(dg)
+	if err != nil {
+		s.log.Error("error registering commands", "error", err)
+		return err
+	}
 	s.welcomeMessage(dg)
+	err = s.replayQueue()
+	if err != nil {
+		s.log.Error("error replaying queue", "error", err)
+		return err
+	}
+	err = s.resumePackets(ctx)
+	if err != nil {
+		s.log.Error("error resuming ibc transfers", "error", err)
+		return err
+	}
 	go s.ProcessRequests(ctx)
 	go s.processResponses(ctx, dg)
+	go s.monitorBalances(ctx)
+	go s.sweepStore(ctx)
+	go s.watchConfig(ctx)
+	if s.client.TreasuryAddress() != "" && s.config().TopUpThreshold.Coins != "" {
+		go s.topUpWallets(ctx)
+	}
+	// the request endpoints send tokens to anyone who can reach them so the api never runs without a token
+	if s.config().APIToken != "" || s.config().AdminToken != "" {
+		go func() {
+			err := s.serveAPI(ctx)
+			if err != nil {
+				s.log.Error("error running api server", "error", err)
+			}
+		}()
+	} else {
+		s.log.Warn("api server disabled, FAUCET_API_TOKEN is not set")
+	}
 
 	<-ctx.Done()
 	s.log.Info("stopping server")
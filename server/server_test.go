@@ -0,0 +1,51 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/public-awesome/faucet/client"
+	"github.com/public-awesome/faucet/config"
+)
+
+func TestProcessRequestsPerChain(t *testing.T) {
+	release := make(chan struct{})
+	slow := newFakeChain(t, func(int) (uint32, bool) {
+		<-release
+		return 0, true
+	})
+	defer close(release)
+	fast := newFakeChain(t, func(int) (uint32, bool) { return 0, true })
+
+	s := testServer(&config.Config{})
+	s.clients = map[string]*client.Client{"": fast.client(t), "slow": slow.client(t)}
+	s.requests = make(chan *SendRequest, 10)
+	s.responses = make(chan *SendResponse, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.ProcessRequests(ctx)
+
+	// the slow chain has a single wallet, its queued requests must not hold the workers of the other chain
+	reqs := batchRequests(t, 4)
+	for _, req := range reqs[:3] {
+		req.Chain = "slow"
+		s.requests <- req
+	}
+	s.requests <- reqs[3]
+	select {
+	case response := <-s.responses:
+		if response.ID != reqs[3].ID || !response.Success {
+			t.Fatalf("expected the request of the other chain to be sent, got %+v", response)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the request of the other chain was not processed")
+	}
+	for deadline := time.Now().Add(5 * time.Second); slow.broadcastCount() == 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if broadcasts := slow.broadcastCount(); broadcasts != 1 {
+		t.Fatalf("expected one send at a time on the slow chain, got %d", broadcasts)
+	}
+}
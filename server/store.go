@@ -2,45 +2,92 @@ package server
 
 import (
 	"errors"
-
-	"github.com/cockroachdb/pebble"
+	"fmt"
+	"path"
+	"time"
 )
 
-type Store struct {
-	db *pebble.DB
+// Store is the key value store used to persist requests, cooldowns and history
+type Store interface {
+	// Get returns the value of the key or ErrNotFound
+	Get(key []byte) ([]byte, error)
+	Set(key, value []byte) error
+	Delete(key []byte) error
+	// Iterate calls fn for every key with the prefix in order until fn returns an error
+	Iterate(prefix []byte, fn func(key, value []byte) error) error
+	// IterateReverse calls fn for every key with the prefix in reverse order until fn returns an error
+	IterateReverse(prefix []byte, fn func(key, value []byte) error) error
+	// Batch returns a set of writes that are applied atomically on Commit
+	Batch() Batch
+	Close() error
 }
 
-var ErrNotFound = errors.New("not found")
+// Batch is a set of writes applied atomically
+type Batch interface {
+	Set(key, value []byte) error
+	Delete(key []byte) error
+	Commit() error
+	// Close releases the batch, the writes are discarded if it wasn't committed. It must be called
+	// once the batch is no longer used, also after Commit
+	Close() error
+}
 
-func NewStore(path string) (*Store, error) {
+// TTLBatch is implemented by the batches of backends that expire keys on their own
+type TTLBatch interface {
+	SetWithTTL(key, value []byte, ttl time.Duration) error
+}
 
-	opts := &pebble.Options{}
-	db, err := pebble.Open(path, opts)
-	if err != nil {
-		return nil, err
-	}
-	return &Store{db: db}, nil
+// Compacter is implemented by backends that need to reclaim the space of deleted keys
+type Compacter interface {
+	Compact(prefix []byte) error
 }
 
-func (s *Store) Close() error {
-	return s.db.Close()
+// setWithTTL uses the native expiry of the backend if there is one, otherwise the key is kept
+// until it's deleted by the sweeper
+func setWithTTL(batch Batch, key, value []byte, ttl time.Duration) error {
+	if ttlBatch, ok := batch.(TTLBatch); ok {
+		return ttlBatch.SetWithTTL(key, value, ttl)
+	}
+	return batch.Set(key, value)
 }
 
-func (s *Store) Get(key []byte) ([]byte, error) {
-	value, closer, err := s.db.Get(key)
+var ErrNotFound = errors.New("not found")
+
+// ErrStopIteration can be returned by an iteration callback to stop without an error
+var ErrStopIteration = errors.New("stop iteration")
 
-	if err != nil {
-		if errors.Is(err, pebble.ErrNotFound) {
-			return nil, ErrNotFound
-		}
-		return nil, err
-	}
-	defer closer.Close()
+const (
+	StoreBackendPebble = "pebble"
+	StoreBackendBadger = "badger"
+	StoreBackendSQLite = "sqlite"
+	StoreBackendMemory = "memory"
+)
 
-	v := append([]byte(nil), value...)
-	return v, nil
+// NewStore opens the store of the backend in the directory
+func NewStore(backend, dir string) (Store, error) {
+	switch backend {
+	case StoreBackendPebble, "":
+		return NewPebbleStore(path.Join(dir, "faucet.db"))
+	case StoreBackendBadger:
+		return NewBadgerStore(path.Join(dir, "faucet.badger"))
+	case StoreBackendSQLite:
+		return NewSQLiteStore(path.Join(dir, "faucet.sqlite"))
+	case StoreBackendMemory:
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown store backend %q", backend)
+	}
 }
 
-func (s *Store) Set(key, value []byte) error {
-	return s.db.Set(key, value, pebble.Sync)
+// prefixUpperBound returns the smallest key greater than every key with the prefix
+func prefixUpperBound(prefix []byte) []byte {
+	end := append([]byte(nil), prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		end[i]++
+		if end[i] != 0 {
+			return end[:i+1]
+		}
+	}
+	// the prefix is all 0xff bytes, there is no upper bound
+	return nil
 }
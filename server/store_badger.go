@@ -0,0 +1,158 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+type BadgerStore struct {
+	db *badger.DB
+}
+
+var _ Store = (*BadgerStore)(nil)
+
+func NewBadgerStore(path string) (*BadgerStore, error) {
+	db, err := badger.Open(badger.DefaultOptions(path).WithLogger(nil))
+	if err != nil {
+		return nil, err
+	}
+	return &BadgerStore{db: db}, nil
+}
+
+func (s *BadgerStore) Close() error {
+	return s.db.Close()
+}
+
+// Compact runs the value log garbage collection until there is nothing left to rewrite, the value
+// log isn't split by key so the prefix is ignored
+func (s *BadgerStore) Compact(prefix []byte) error {
+	for {
+		err := s.db.RunValueLogGC(0.5)
+		if errors.Is(err, badger.ErrNoRewrite) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *BadgerStore) Get(key []byte) ([]byte, error) {
+	var value []byte
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+		value, err = item.ValueCopy(nil)
+		return err
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, ErrNotFound
+	}
+	return value, err
+}
+
+func (s *BadgerStore) Set(key, value []byte) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(key, value)
+	})
+}
+
+func (s *BadgerStore) Delete(key []byte) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(key)
+	})
+}
+
+func (s *BadgerStore) Iterate(prefix []byte, fn func(key, value []byte) error) error {
+	return s.iterate(prefix, false, fn)
+}
+
+func (s *BadgerStore) IterateReverse(prefix []byte, fn func(key, value []byte) error) error {
+	return s.iterate(prefix, true, fn)
+}
+
+func (s *BadgerStore) iterate(prefix []byte, reverse bool, fn func(key, value []byte) error) error {
+	return s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Reverse = reverse
+		if !reverse {
+			opts.Prefix = prefix
+		}
+		iter := txn.NewIterator(opts)
+		defer iter.Close()
+
+		seek := prefix
+		if reverse {
+			// reverse iteration starts at the last key lower or equal than the seek key
+			seek = prefixUpperBound(prefix)
+			if seek == nil {
+				seek = bytes.Repeat([]byte{0xff}, len(prefix)+1)
+			}
+		}
+		for iter.Seek(seek); iter.Valid(); iter.Next() {
+			item := iter.Item()
+			if !bytes.HasPrefix(item.Key(), prefix) {
+				// the seek key itself may exist when iterating in reverse
+				if reverse && bytes.Compare(item.Key(), prefix) > 0 {
+					continue
+				}
+				break
+			}
+			value, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			err = fn(item.KeyCopy(nil), value)
+			if errors.Is(err, ErrStopIteration) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BadgerStore) Batch() Batch {
+	return &badgerBatch{batch: s.db.NewWriteBatch()}
+}
+
+type badgerBatch struct {
+	batch *badger.WriteBatch
+	// done is set once the batch was flushed or canceled, a write batch can't be canceled after that
+	done bool
+}
+
+func (b *badgerBatch) Set(key, value []byte) error {
+	return b.batch.Set(key, value)
+}
+
+func (b *badgerBatch) SetWithTTL(key, value []byte, ttl time.Duration) error {
+	return b.batch.SetEntry(badger.NewEntry(key, value).WithTTL(ttl))
+}
+
+func (b *badgerBatch) Delete(key []byte) error {
+	return b.batch.Delete(key)
+}
+
+func (b *badgerBatch) Commit() error {
+	err := b.batch.Flush()
+	if err == nil {
+		b.done = true
+	}
+	return err
+}
+
+func (b *badgerBatch) Close() error {
+	if !b.done {
+		b.done = true
+		b.batch.Cancel()
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// MemoryStore keeps everything in memory, it is meant for tests and local development
+type MemoryStore struct {
+	mu   sync.RWMutex
+	data map[string][]byte
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{data: make(map[string][]byte)}
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+func (s *MemoryStore) Get(key []byte) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok := s.data[string(key)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), value...), nil
+}
+
+func (s *MemoryStore) Set(key, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[string(key)] = append([]byte(nil), value...)
+	return nil
+}
+
+func (s *MemoryStore) Delete(key []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, string(key))
+	return nil
+}
+
+func (s *MemoryStore) Iterate(prefix []byte, fn func(key, value []byte) error) error {
+	return s.iterate(prefix, false, fn)
+}
+
+func (s *MemoryStore) IterateReverse(prefix []byte, fn func(key, value []byte) error) error {
+	return s.iterate(prefix, true, fn)
+}
+
+// iterate works on a snapshot of the matching keys so fn can write to the store
+func (s *MemoryStore) iterate(prefix []byte, reverse bool, fn func(key, value []byte) error) error {
+	type entry struct {
+		key   string
+		value []byte
+	}
+	s.mu.RLock()
+	var entries []entry
+	for key, value := range s.data {
+		if strings.HasPrefix(key, string(prefix)) {
+			entries = append(entries, entry{key: key, value: value})
+		}
+	}
+	s.mu.RUnlock()
+	slices.SortFunc(entries, func(a, b entry) int {
+		return strings.Compare(a.key, b.key)
+	})
+	if reverse {
+		slices.Reverse(entries)
+	}
+	for _, e := range entries {
+		err := fn([]byte(e.key), append([]byte(nil), e.value...))
+		if errors.Is(err, ErrStopIteration) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) Batch() Batch {
+	return &memoryBatch{store: s}
+}
+
+type memoryBatch struct {
+	store  *MemoryStore
+	writes []memoryWrite
+}
+
+// memoryWrite is a pending write of a batch, a nil value deletes the key
+type memoryWrite struct {
+	key   string
+	value []byte
+}
+
+func (b *memoryBatch) Set(key, value []byte) error {
+	b.writes = append(b.writes, memoryWrite{key: string(key), value: append([]byte{}, value...)})
+	return nil
+}
+
+func (b *memoryBatch) Delete(key []byte) error {
+	b.writes = append(b.writes, memoryWrite{key: string(key)})
+	return nil
+}
+
+func (b *memoryBatch) Commit() error {
+	b.store.mu.Lock()
+	defer b.store.mu.Unlock()
+	for _, w := range b.writes {
+		if w.value == nil {
+			delete(b.store.data, w.key)
+		} else {
+			b.store.data[w.key] = w.value
+		}
+	}
+	return nil
+}
+
+func (b *memoryBatch) Close() error {
+	b.writes = nil
+	return nil
+}
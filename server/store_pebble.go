@@ -0,0 +1,120 @@
+package server
+
+import (
+	"errors"
+
+	"github.com/cockroachdb/pebble"
+)
+
+type PebbleStore struct {
+	db *pebble.DB
+}
+
+var _ Store = (*PebbleStore)(nil)
+
+func NewPebbleStore(path string) (*PebbleStore, error) {
+	opts := &pebble.Options{}
+	db, err := pebble.Open(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &PebbleStore{db: db}, nil
+}
+
+func (s *PebbleStore) Close() error {
+	return s.db.Close()
+}
+
+// Compact compacts the keys with the prefix so the tombstones of deleted keys are dropped
+func (s *PebbleStore) Compact(prefix []byte) error {
+	end := prefixUpperBound(prefix)
+	if end == nil {
+		end = []byte{0xff}
+	}
+	return s.db.Compact(prefix, end, true)
+}
+
+func (s *PebbleStore) Get(key []byte) ([]byte, error) {
+	value, closer, err := s.db.Get(key)
+
+	if err != nil {
+		if errors.Is(err, pebble.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	defer closer.Close()
+
+	v := append([]byte(nil), value...)
+	return v, nil
+}
+
+func (s *PebbleStore) Set(key, value []byte) error {
+	return s.db.Set(key, value, pebble.Sync)
+}
+
+func (s *PebbleStore) Delete(key []byte) error {
+	return s.db.Delete(key, pebble.Sync)
+}
+
+func (s *PebbleStore) Iterate(prefix []byte, fn func(key, value []byte) error) error {
+	return s.iterate(prefix, false, fn)
+}
+
+func (s *PebbleStore) IterateReverse(prefix []byte, fn func(key, value []byte) error) error {
+	return s.iterate(prefix, true, fn)
+}
+
+func (s *PebbleStore) iterate(prefix []byte, reverse bool, fn func(key, value []byte) error) error {
+	iter, err := s.db.NewIter(&pebble.IterOptions{
+		LowerBound: prefix,
+		UpperBound: prefixUpperBound(prefix),
+	})
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+	valid, next := iter.First, iter.Next
+	if reverse {
+		valid, next = iter.Last, iter.Prev
+	}
+	for ok := valid(); ok; ok = next() {
+		err := fn(append([]byte(nil), iter.Key()...), append([]byte(nil), iter.Value()...))
+		if errors.Is(err, ErrStopIteration) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return iter.Error()
+}
+
+func (s *PebbleStore) Batch() Batch {
+	return &pebbleBatch{batch: s.db.NewBatch()}
+}
+
+type pebbleBatch struct {
+	batch  *pebble.Batch
+	closed bool
+}
+
+func (b *pebbleBatch) Set(key, value []byte) error {
+	return b.batch.Set(key, value, nil)
+}
+
+func (b *pebbleBatch) Delete(key []byte) error {
+	return b.batch.Delete(key, nil)
+}
+
+func (b *pebbleBatch) Commit() error {
+	return b.batch.Commit(pebble.Sync)
+}
+
+func (b *pebbleBatch) Close() error {
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	return b.batch.Close()
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteStore keeps the data in a single kv table so it can be inspected with the sqlite3 tool
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+var _ Store = (*SQLiteStore)(nil)
+
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=synchronous(FULL)", path))
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS kv (key BLOB PRIMARY KEY, value BLOB NOT NULL) WITHOUT ROWID")
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteStore) Get(key []byte) ([]byte, error) {
+	var value []byte
+	err := s.db.QueryRow("SELECT value FROM kv WHERE key = ?", key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return value, err
+}
+
+func (s *SQLiteStore) Set(key, value []byte) error {
+	_, err := s.db.Exec("INSERT INTO kv (key, value) VALUES (?, ?) ON CONFLICT (key) DO UPDATE SET value = excluded.value", key, nonNil(value))
+	return err
+}
+
+func (s *SQLiteStore) Delete(key []byte) error {
+	_, err := s.db.Exec("DELETE FROM kv WHERE key = ?", key)
+	return err
+}
+
+func (s *SQLiteStore) Iterate(prefix []byte, fn func(key, value []byte) error) error {
+	return s.iterate(prefix, "ASC", fn)
+}
+
+func (s *SQLiteStore) IterateReverse(prefix []byte, fn func(key, value []byte) error) error {
+	return s.iterate(prefix, "DESC", fn)
+}
+
+func (s *SQLiteStore) iterate(prefix []byte, order string, fn func(key, value []byte) error) error {
+	query := "SELECT key, value FROM kv WHERE key >= ?"
+	args := []any{nonNil(prefix)}
+	if end := prefixUpperBound(prefix); end != nil {
+		query += " AND key < ?"
+		args = append(args, end)
+	}
+	rows, err := s.db.Query(query+" ORDER BY key "+order, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key, value []byte
+		err := rows.Scan(&key, &value)
+		if err != nil {
+			return err
+		}
+		err = fn(key, value)
+		if errors.Is(err, ErrStopIteration) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (s *SQLiteStore) Batch() Batch {
+	return &sqliteBatch{db: s.db}
+}
+
+type sqliteBatch struct {
+	db     *sql.DB
+	writes []memoryWrite
+}
+
+func (b *sqliteBatch) Set(key, value []byte) error {
+	b.writes = append(b.writes, memoryWrite{key: string(key), value: append([]byte{}, value...)})
+	return nil
+}
+
+func (b *sqliteBatch) Delete(key []byte) error {
+	b.writes = append(b.writes, memoryWrite{key: string(key)})
+	return nil
+}
+
+func (b *sqliteBatch) Commit() error {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, w := range b.writes {
+		if w.value == nil {
+			_, err = tx.Exec("DELETE FROM kv WHERE key = ?", []byte(w.key))
+		} else {
+			_, err = tx.Exec("INSERT INTO kv (key, value) VALUES (?, ?) ON CONFLICT (key) DO UPDATE SET value = excluded.value", []byte(w.key), w.value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (b *sqliteBatch) Close() error {
+	b.writes = nil
+	return nil
+}
+
+// nonNil avoids storing NULL for empty values since the driver maps nil slices to NULL
+func nonNil(b []byte) []byte {
+	if b == nil {
+		return []byte{}
+	}
+	return b
+}
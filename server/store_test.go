@@ -0,0 +1,100 @@
+package server
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestStoreBackends(t *testing.T) {
+	backends := map[string]func(dir string) (Store, error){
+		StoreBackendMemory: func(string) (Store, error) { return NewMemoryStore(), nil },
+		StoreBackendPebble: func(dir string) (Store, error) { return NewPebbleStore(filepath.Join(dir, "pebble")) },
+		StoreBackendBadger: func(dir string) (Store, error) { return NewBadgerStore(filepath.Join(dir, "badger")) },
+		StoreBackendSQLite: func(dir string) (Store, error) { return NewSQLiteStore(filepath.Join(dir, "faucet.sqlite")) },
+	}
+	for name, open := range backends {
+		t.Run(name, func(t *testing.T) {
+			store, err := open(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+
+			_, err = store.Get([]byte("missing"))
+			if !errors.Is(err, ErrNotFound) {
+				t.Fatalf("expected ErrNotFound, got %v", err)
+			}
+			for _, key := range []string{"a/1", "a/2", "a/3", "a0", "b", "a"} {
+				if err := store.Set([]byte(key), []byte("v"+key)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			value, err := store.Get([]byte("a/2"))
+			if err != nil || string(value) != "va/2" {
+				t.Fatalf("unexpected value %q, %v", value, err)
+			}
+
+			batch := store.Batch()
+			defer batch.Close()
+			if err := batch.Delete([]byte("a/1")); err != nil {
+				t.Fatal(err)
+			}
+			if err := batch.Set([]byte("a/4"), []byte("va/4")); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.Get([]byte("a/4")); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("batch written before commit: %v", err)
+			}
+			if err := batch.Commit(); err != nil {
+				t.Fatal(err)
+			}
+
+			// a batch closed without a commit discards its writes
+			discarded := store.Batch()
+			if err := discarded.Set([]byte("a/5"), []byte("va/5")); err != nil {
+				t.Fatal(err)
+			}
+			if err := discarded.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.Get([]byte("a/5")); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("closed batch was written: %v", err)
+			}
+
+			var keys []string
+			collect := func(key, value []byte) error {
+				keys = append(keys, string(key))
+				return nil
+			}
+			if err := store.Iterate([]byte("a/"), collect); err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{"a/2", "a/3", "a/4"}; !slices.Equal(keys, want) {
+				t.Fatalf("expected %v, got %v", want, keys)
+			}
+
+			keys = nil
+			err = store.IterateReverse([]byte("a/"), func(key, value []byte) error {
+				if len(keys) == 2 {
+					return ErrStopIteration
+				}
+				return collect(key, value)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{"a/4", "a/3"}; !slices.Equal(keys, want) {
+				t.Fatalf("expected %v, got %v", want, keys)
+			}
+
+			if err := store.Delete([]byte("b")); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.Get([]byte("b")); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("expected ErrNotFound after delete, got %v", err)
+			}
+		})
+	}
+}
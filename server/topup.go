@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// channelDenoms returns the denoms handed out by the channels of the default chain
+func (s *Server) channelDenoms() map[string]bool {
+	denoms := make(map[string]bool)
+	for _, denom := range s.config().Denoms("") {
+		denoms[denom] = true
+	}
+	return denoms
+}
+
+// topUpWallets periodically refills the hot wallets from the treasury when they run low
+func (s *Server) topUpWallets(ctx context.Context) {
+	threshold, err := sdk.ParseCoinsNormalized(s.config().TopUpThreshold.Coins)
+	if err != nil {
+		s.log.Error("invalid top-up threshold", "error", err, "threshold", s.config().TopUpThreshold.Coins)
+		return
+	}
+	amount, err := sdk.ParseCoinsNormalized(s.config().TopUpAmount.Coins)
+	if err != nil {
+		s.log.Error("invalid top-up amount", "error", err, "amount", s.config().TopUpAmount.Coins)
+		return
+	}
+	s.log.Info("starting top-up loop", "treasury", s.client.TreasuryAddress(), "threshold", threshold.String(), "amount", amount.String())
+
+	lastTopUp := make(map[string]time.Time)
+	ticker := time.NewTicker(s.config().TopUpInterval)
+	defer ticker.Stop()
+	for {
+		s.checkTopUps(ctx, threshold, amount, lastTopUp)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			s.log.Info("stopping top-up loop")
+			return
+		}
+	}
+}
+
+func (s *Server) checkTopUps(ctx context.Context, threshold, amount sdk.Coins, lastTopUp map[string]time.Time) {
+	denoms := s.channelDenoms()
+	balances, err := s.client.WalletBalances(ctx)
+	if err != nil {
+		s.log.Error("error fetching wallet balances", "error", err)
+		return
+	}
+	for _, wallet := range balances {
+		needed := sdk.NewCoins()
+		for _, coin := range threshold {
+			if !denoms[coin.Denom] || wallet.Balances.AmountOf(coin.Denom).GTE(coin.Amount) {
+				continue
+			}
+			if topUp := amount.AmountOf(coin.Denom); topUp.IsPositive() {
+				needed = needed.Add(sdk.NewCoin(coin.Denom, topUp))
+			}
+		}
+		if needed.IsZero() {
+			continue
+		}
+		if last, ok := lastTopUp[wallet.Address]; ok && time.Since(last) < s.config().TopUpCooldown {
+			s.log.Warn("wallet below top-up threshold but was topped up recently", "address", wallet.Address, "balances", wallet.Balances.String(), "last_top_up", last)
+			continue
+		}
+		lastTopUp[wallet.Address] = time.Now()
+		result, err := s.client.TopUp(ctx, wallet.Address, needed)
+		if err != nil {
+			s.log.Error("error topping up wallet", "error", err, "address", wallet.Address, "amount", needed.String())
+			continue
+		}
+		s.log.Info("topped up wallet", "address", wallet.Address, "amount", needed.String(), "balances", wallet.Balances.String(), "tx_hash", result.TxHash)
+	}
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/public-awesome/faucet/client"
+	"github.com/public-awesome/faucet/config"
+)
+
+func TestCheckTopUps(t *testing.T) {
+	low := sdk.NewCoins(sdk.NewInt64Coin("ustars", 10))
+	for _, tc := range []struct {
+		name              string
+		threshold, amount string
+		// balances are the balances of the hot wallets by index, the others hold the default balance
+		balances map[int]sdk.Coins
+		// broadcast is the outcome of the top-up transactions
+		broadcast func(n int) (uint32, bool)
+		// recent are the wallets topped up within the cooldown
+		recent     []int
+		broadcasts int
+		toppedUp   []int
+	}{
+		{name: "below threshold", threshold: "1000ustars", amount: "5000ustars", balances: map[int]sdk.Coins{1: low}, broadcasts: 1, toppedUp: []int{1}},
+		{name: "above threshold", threshold: "1000ustars", amount: "5000ustars"},
+		{name: "denom not handed out", threshold: "1000uatom", amount: "5000uatom", balances: map[int]sdk.Coins{0: low, 1: low}},
+		{name: "no top-up amount", threshold: "1000ustars", amount: "5000uatom", balances: map[int]sdk.Coins{0: low}},
+		{name: "topped up recently", threshold: "1000ustars", amount: "5000ustars", balances: map[int]sdk.Coins{0: low}, recent: []int{0}, toppedUp: []int{0}},
+		{
+			name: "treasury failure", threshold: "1000ustars", amount: "5000ustars", balances: map[int]sdk.Coins{0: low, 1: low},
+			broadcast: func(int) (uint32, bool) { return 5, true }, broadcasts: 2, toppedUp: []int{0, 1},
+		},
+		{
+			name: "treasury unreachable", threshold: "1000ustars", amount: "5000ustars", balances: map[int]sdk.Coins{0: low},
+			broadcast: func(int) (uint32, bool) { return 0, false }, broadcasts: 1, toppedUp: []int{0},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			broadcast := tc.broadcast
+			if broadcast == nil {
+				broadcast = func(int) (uint32, bool) { return 0, true }
+			}
+			chain := newFakeChain(t, broadcast)
+			index := uint32(2)
+			c := chain.client(t, client.WithAccounts(2), client.WithTreasuryIndex(&index))
+			addresses := c.Addresses()
+			for i, balances := range tc.balances {
+				chain.setBalance(addresses[i], balances)
+			}
+			s := testServer(&config.Config{
+				Channels:      []config.ChannelProfile{{Name: "faucet", Coins: "10ustars"}},
+				TopUpCooldown: 10 * time.Minute,
+			})
+			s.client = c
+			lastTopUp := make(map[string]time.Time)
+			for _, i := range tc.recent {
+				lastTopUp[addresses[i]] = time.Now().Add(-time.Minute)
+			}
+
+			threshold, err := sdk.ParseCoinsNormalized(tc.threshold)
+			if err != nil {
+				t.Fatal(err)
+			}
+			amount, err := sdk.ParseCoinsNormalized(tc.amount)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.checkTopUps(context.Background(), threshold, amount, lastTopUp)
+			if broadcasts := chain.broadcastCount(); broadcasts != tc.broadcasts {
+				t.Fatalf("expected %d top-ups, got %d", tc.broadcasts, broadcasts)
+			}
+			// failed top-ups also wait for the cooldown so a failing treasury isn't retried on every check
+			if len(lastTopUp) != len(tc.toppedUp) {
+				t.Fatalf("expected %d wallets to be topped up, got %v", len(tc.toppedUp), lastTopUp)
+			}
+			for _, i := range tc.toppedUp {
+				if _, ok := lastTopUp[addresses[i]]; !ok {
+					t.Fatalf("expected wallet %d to be topped up", i)
+				}
+			}
+		})
+	}
+}
+
+func TestTopUpWallets(t *testing.T) {
+	// the wallets are checked right away, the loop is stopped once the top-up is broadcasted
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	chain := newFakeChain(t, func(int) (uint32, bool) {
+		cancel()
+		return 0, true
+	})
+	index := uint32(1)
+	c := chain.client(t, client.WithTreasuryIndex(&index))
+	chain.setBalance(c.Addresses()[0], sdk.NewCoins(sdk.NewInt64Coin("ustars", 10)))
+	cfg := &config.Config{
+		Channels:       []config.ChannelProfile{{Name: "faucet", Coins: "10ustars"}},
+		TopUpThreshold: config.ChannelConfig{Coins: "1000ustars"},
+		TopUpAmount:    config.ChannelConfig{Coins: "ustars"},
+		TopUpInterval:  time.Hour,
+		TopUpCooldown:  10 * time.Minute,
+	}
+	s := testServer(cfg)
+	s.client = c
+
+	s.topUpWallets(ctx)
+	if broadcasts := chain.broadcastCount(); broadcasts != 0 {
+		t.Fatalf("expected an invalid top-up amount to stop the loop, got %d top-ups", broadcasts)
+	}
+	cfg.TopUpAmount.Coins = "5000ustars"
+	s.topUpWallets(ctx)
+	if broadcasts := chain.broadcastCount(); broadcasts != 1 {
+		t.Fatalf("expected the wallet to be topped up on start, got %d top-ups", broadcasts)
+	}
+}